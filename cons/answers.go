@@ -0,0 +1,277 @@
+package cons
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Answers supplies menu choices and field values so Choose and Entry can run without a human.
+// Menus are keyed by title and forms by title and field prompt.  Forms run with StartEntry, which
+// have no title, are keyed by the prompt of their first field.  Each answer is used once, in order.
+//
+// Answers are written as JSON:
+//
+//	{"Main Menu": ["2", "Quit"], "Customer": {"Name": "Bob", "Age": 42}}
+//
+// or as "title: choice" lines with each form's "prompt: value" lines indented below its "title:" line:
+//
+//	Main Menu: 2
+//	Customer:
+//	  Name: Bob
+//	  Age: 42
+//	Main Menu: Quit
+type Answers struct {
+	menus map[string][]string
+	forms map[string][]map[string]string
+}
+
+// AnswerError reports an answer that could not be used
+type AnswerError struct {
+	// Title is the menu or form title
+	Title string
+	// Prompt is the field prompt, or empty for a menu
+	Prompt string
+	// Value is the rejected answer
+	Value string
+	// Reason says why the answer was rejected
+	Reason string
+}
+
+func (e *AnswerError) Error() string {
+	if e.Prompt != "" {
+		return fmt.Sprintf("cons: answer %q for %q field %q: %s", e.Value, e.Title, e.Prompt, e.Reason)
+	}
+	if e.Value != "" {
+		return fmt.Sprintf("cons: answer %q for %q: %s", e.Value, e.Title, e.Reason)
+	}
+	return fmt.Sprintf("cons: %q: %s", e.Title, e.Reason)
+}
+
+// AnswersEnv is the environment variable holding an answer file path or inline JSON answers.  If they cannot be
+// read, menus and forms return the error.
+const AnswersEnv = "CONS_ANSWERS"
+
+var answers *Answers
+var answersErr error
+
+func init() {
+	if value := os.Getenv(AnswersEnv); value != "" {
+		useAnswersEnv(value)
+	}
+}
+
+// useAnswersEnv uses the answers in an AnswersEnv value.  Answers that cannot be read or parsed are still used,
+// so menus and forms return the error instead of waiting for a keyboard that may not be there.
+func useAnswersEnv(value string) {
+	var a *Answers
+	var err error
+	if strings.HasPrefix(strings.TrimSpace(value), "{") {
+		a, err = ParseAnswers(value)
+	} else {
+		a, err = LoadAnswers(value)
+	}
+	if err != nil {
+		a = &Answers{menus: map[string][]string{}, forms: map[string][]map[string]string{}}
+		err = fmt.Errorf("cons: %s: %w", AnswersEnv, err)
+	}
+	answers, answersErr = a, err
+}
+
+// GetAnswers returns the answers in use, or nil if menus and forms are interactive
+func GetAnswers() *Answers {
+	return answers
+}
+
+// SetAnswers makes Choose and Entry use answers instead of the keyboard.  nil makes them interactive again.
+func SetAnswers(a *Answers) {
+	answers = a
+	answersErr = nil
+}
+
+// LoadAnswers reads an answer file
+func LoadAnswers(path string) (*Answers, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	a, err := ParseAnswers(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return a, nil
+}
+
+// ParseAnswers reads answers written as JSON or as indented "key: value" lines
+func ParseAnswers(text string) (*Answers, error) {
+	a := &Answers{menus: map[string][]string{}, forms: map[string][]map[string]string{}}
+	if strings.HasPrefix(strings.TrimSpace(text), "{") {
+		return a, a.parseJSON(text)
+	}
+	return a, a.parseLines(text)
+}
+
+// parseJSON reads answers from a JSON object
+func (a *Answers) parseJSON(text string) error {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &doc); err != nil {
+		return err
+	}
+	for title, value := range doc {
+		list, ok := value.([]interface{})
+		if !ok {
+			list = []interface{}{value}
+		}
+		for _, item := range list {
+			if form, ok := item.(map[string]interface{}); ok {
+				values := map[string]string{}
+				for prompt, v := range form {
+					s, ok := jsonScalar(v)
+					if !ok {
+						return fmt.Errorf("answer for %q field %q must be a string or number", title, prompt)
+					}
+					values[prompt] = s
+				}
+				a.forms[title] = append(a.forms[title], values)
+				continue
+			}
+			s, ok := jsonScalar(item)
+			if !ok {
+				return fmt.Errorf("answer for %q must be a string, number, object or list of them", title)
+			}
+			a.menus[title] = append(a.menus[title], s)
+		}
+	}
+	return nil
+}
+
+// jsonScalar converts a JSON string, number or boolean to text
+func jsonScalar(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	return "", false
+}
+
+// parseLines reads answers from "title: choice" lines and "title:" lines followed by indented "prompt: value" lines
+func (a *Answers) parseLines(text string) error {
+	var form map[string]string
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		key, value, ok := splitAnswer(trimmed)
+		if !ok {
+			return fmt.Errorf("line %d: expected \"key: value\"", n+1)
+		}
+		if trimmed != line {
+			if form == nil {
+				return fmt.Errorf("line %d: indented answer outside a form", n+1)
+			}
+			form[key] = value
+			continue
+		}
+		if value == "" {
+			form = map[string]string{}
+			a.forms[key] = append(a.forms[key], form)
+			continue
+		}
+		form = nil
+		a.menus[key] = append(a.menus[key], value)
+	}
+	return nil
+}
+
+// splitAnswer splits a "key: value" line, removing quotes around the value
+func splitAnswer(line string) (string, string, bool) {
+	i := strings.Index(line, ": ")
+	if i < 0 {
+		if !strings.HasSuffix(line, ":") {
+			return "", "", false
+		}
+		return strings.TrimSpace(line[:len(line)-1]), "", true
+	}
+	key := strings.TrimSpace(line[:i])
+	value := strings.TrimSpace(line[i+2:])
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return key, value, key != ""
+}
+
+// choose returns the next answer for a menu as an item number
+func (a *Answers) choose(title string, items []string) (int, error) {
+	if answersErr != nil {
+		return 0, answersErr
+	}
+	list := a.menus[title]
+	if len(list) == 0 {
+		return 0, &AnswerError{Title: title, Reason: "no answer for menu"}
+	}
+	value := list[0]
+	a.menus[title] = list[1:]
+	if c, err := strconv.Atoi(value); err == nil {
+		if c >= 1 && c <= len(items) {
+			return c, nil
+		}
+		return 0, &AnswerError{Title: title, Value: value, Reason: fmt.Sprintf("choice must be 1-%d", len(items))}
+	}
+	for i, item := range items {
+		if strings.EqualFold(strings.TrimSpace(item), value) {
+			return i + 1, nil
+		}
+	}
+	return 0, &AnswerError{Title: title, Value: value, Reason: "no such menu item"}
+}
+
+// entry fills fields from the next answer for a form, applying the field validators
+func (a *Answers) entry(title string, fields []InputField) error {
+	if answersErr != nil {
+		return answersErr
+	}
+	if title == "" && len(fields) > 0 {
+		title = fields[0].prompt
+	}
+	list := a.forms[title]
+	if len(list) == 0 {
+		return &AnswerError{Title: title, Reason: "no answer for form"}
+	}
+	values := list[0]
+	a.forms[title] = list[1:]
+	for prompt := range values {
+		found := false
+		for index := range fields {
+			found = found || fields[index].prompt == prompt
+		}
+		if !found {
+			return &AnswerError{Title: title, Prompt: prompt, Value: values[prompt], Reason: "no such field"}
+		}
+	}
+	for index := range fields {
+		field := &fields[index]
+		value, ok := values[field.prompt]
+		if !ok {
+			value = field.value
+		}
+		if msg := checkFieldText(field, value); msg != "" {
+			return &AnswerError{Title: title, Prompt: field.prompt, Value: value, Reason: msg}
+		}
+		previous := field.value
+		field.value = value
+		if !isFieldValid(field) {
+			field.value = previous
+			return &AnswerError{Title: title, Prompt: field.prompt, Value: value, Reason: "rejected by field validation"}
+		}
+	}
+	trimFields(fields)
+	return nil
+}
@@ -0,0 +1,352 @@
+package cons
+
+import (
+	"sort"
+	"strings"
+)
+
+// Line weights of each direction out of a box drawing cell
+const (
+	lineNone   = '0'
+	lineLight  = '1'
+	lineHeavy  = '2'
+	lineDouble = '3'
+	lineASCII  = '4'
+)
+
+// boxRunes are the box drawing characters and the weight of the line going up, right, down and left from each
+var boxRunes = map[rune]string{
+	'─': "0101", '━': "0202", '│': "1010", '┃': "2020", '┌': "0110", '┍': "0210", '┎': "0120", '┏': "0220",
+	'┐': "0011", '┑': "0012", '┒': "0021", '┓': "0022", '└': "1100", '┕': "1200", '┖': "2100", '┗': "2200",
+	'┘': "1001", '┙': "1002", '┚': "2001", '┛': "2002", '├': "1110", '┝': "1210", '┞': "2110", '┟': "1120",
+	'┠': "2120", '┡': "2210", '┢': "1220", '┣': "2220", '┤': "1011", '┥': "1012", '┦': "2011", '┧': "1021",
+	'┨': "2021", '┩': "2012", '┪': "1022", '┫': "2022", '┬': "0111", '┭': "0112", '┮': "0211", '┯': "0212",
+	'┰': "0121", '┱': "0122", '┲': "0221", '┳': "0222", '┴': "1101", '┵': "1102", '┶': "1201", '┷': "1202",
+	'┸': "2101", '┹': "2102", '┺': "2201", '┻': "2202", '┼': "1111", '┽': "1112", '┾': "1211", '┿': "1212",
+	'╀': "2111", '╁': "1121", '╂': "2121", '╃': "2112", '╄': "2211", '╅': "1122", '╆': "1221", '╇': "2212",
+	'╈': "1222", '╉': "2122", '╊': "2221", '╋': "2222", '═': "0303", '║': "3030", '╒': "0310", '╓': "0130",
+	'╔': "0330", '╕': "0013", '╖': "0031", '╗': "0033", '╘': "1300", '╙': "3100", '╚': "3300", '╛': "1003",
+	'╜': "3001", '╝': "3003", '╞': "1310", '╟': "3130", '╠': "3330", '╡': "1013", '╢': "3031", '╣': "3033",
+	'╤': "0313", '╥': "0131", '╦': "0333", '╧': "1303", '╨': "3101", '╩': "3303", '╪': "1313", '╫': "3131",
+	'╬': "3333", '╴': "0001", '╵': "1000", '╶': "0100", '╷': "0010", '╸': "0002", '╹': "2000", '╺': "0200",
+	'╻': "0020", '╼': "0201", '╽': "1020", '╾': "0102", '╿': "2010",
+}
+
+// roundedRunes are the rounded light corners
+var roundedRunes = map[rune]string{'╭': "0110", '╮': "0011", '╯': "1001", '╰': "1100"}
+
+// boxJoins finds the character for the weights of the lines going up, right, down and left
+var boxJoins = map[string]rune{}
+
+func init() {
+	for r, weights := range boxRunes {
+		boxJoins[weights] = r
+	}
+}
+
+// styleWeights returns the weights of the horizontal and vertical lines of a line style
+func styleWeights(style int) (horizontal byte, vertical byte) {
+	switch style {
+	case LineStyleNone:
+		return lineNone, lineNone
+	case LineStyleDouble:
+		return lineDouble, lineDouble
+	case LineStyleHeavy:
+		return lineHeavy, lineHeavy
+	case LineStyleASCII:
+		return lineASCII, lineASCII
+	case LineStyleDoubleHorizontal:
+		return lineDouble, lineLight
+	case LineStyleDoubleVertical:
+		return lineLight, lineDouble
+	}
+	return lineLight, lineLight
+}
+
+// joinRune returns the character where lines of the weights going up, right, down and left meet.  Unicode has
+// no characters joining heavy and double lines, or lines of different weights on one axis with double lines,
+// so those are drawn with the nearest character that exists.
+func joinRune(weights string, rounded bool) rune {
+	if weights == "0000" {
+		return ' '
+	}
+	if strings.ContainsRune(weights, lineASCII) {
+		switch {
+		case weights[0] == lineNone && weights[2] == lineNone:
+			return '-'
+		case weights[1] == lineNone && weights[3] == lineNone:
+			return '|'
+		}
+		return '+'
+	}
+	if rounded {
+		for r, corner := range roundedRunes {
+			if corner == weights {
+				return r
+			}
+		}
+	}
+	heavyAsLight := strings.ReplaceAll(weights, string(lineHeavy), string(lineLight))
+	for _, candidate := range []string{weights, evenAxes(weights), heavyAsLight, evenAxes(heavyAsLight)} {
+		if r, ok := boxJoins[candidate]; ok {
+			return r
+		}
+	}
+	light := []byte(weights)
+	for i := range light {
+		if light[i] != lineNone {
+			light[i] = lineLight
+		}
+	}
+	return boxJoins[string(light)]
+}
+
+// evenAxes gives both ends of each axis the heavier weight of the two
+func evenAxes(weights string) string {
+	w := []byte(weights)
+	for i := 0; i < 2; i++ {
+		heavier := w[i]
+		if w[i+2] > heavier {
+			heavier = w[i+2]
+		}
+		for _, j := range []int{i, i + 2} {
+			if w[j] != lineNone {
+				w[j] = heavier
+			}
+		}
+	}
+	return string(w)
+}
+
+// BoxChars are the characters for drawing a box in a line style
+type BoxChars struct {
+	TopLeft     string
+	TopRight    string
+	BottomLeft  string
+	BottomRight string
+	Horizontal  string
+	Vertical    string
+	// TopJoin, BottomJoin, LeftJoin and RightJoin join a divider to the border, i.e. ┬ ┴ ├ ┤
+	TopJoin    string
+	BottomJoin string
+	LeftJoin   string
+	RightJoin  string
+	// Cross is where two dividers cross
+	Cross string
+}
+
+// GetBoxChars returns the characters for drawing a box in style.  An unknown style draws single lines.
+func GetBoxChars(style int) BoxChars {
+	if style != LineStyleNone && !canDrawUnicode() {
+		style = LineStyleASCII
+	}
+	h, v := styleWeights(style)
+	rounded := style == LineStyleRounded
+	join := func(up byte, right byte, down byte, left byte) string {
+		return string(joinRune(string([]byte{up, right, down, left}), rounded))
+	}
+	return BoxChars{
+		TopLeft: join(lineNone, h, v, lineNone), TopRight: join(lineNone, lineNone, v, h),
+		BottomLeft: join(v, h, lineNone, lineNone), BottomRight: join(v, lineNone, lineNone, h),
+		Horizontal: join(lineNone, h, lineNone, h), Vertical: join(v, lineNone, v, lineNone),
+		TopJoin: join(lineNone, h, v, h), BottomJoin: join(v, h, lineNone, h),
+		LeftJoin: join(v, h, v, lineNone), RightJoin: join(v, lineNone, v, h), Cross: join(v, h, v, h),
+	}
+}
+
+// lineCell is a cell lines pass through
+type lineCell struct {
+	weights []byte
+	rounded bool
+}
+
+// Lines collects horizontal and vertical lines and draws them with the right junction characters where they
+// meet or cross, i.e. ┼ ╬ ╪.  A line ends in the middle of its last cell, so a box is four lines meeting at
+// the corners.
+type Lines struct {
+	// Merge joins the lines to the box drawing characters already on the screen where they cover them
+	Merge bool
+	cells map[[2]int]*lineCell
+}
+
+// NewLines creates an empty set of lines
+func NewLines() *Lines {
+	return &Lines{cells: map[[2]int]*lineCell{}}
+}
+
+// add adds a line of weight going in direction from the cell at row, col
+func (l *Lines) add(row int, col int, direction int, weight byte, rounded bool) {
+	if weight == lineNone {
+		return
+	}
+	cell, ok := l.cells[[2]int{row, col}]
+	if !ok {
+		cell = &lineCell{weights: []byte("0000")}
+		l.cells[[2]int{row, col}] = cell
+	}
+	cell.weights[direction] = weight
+	cell.rounded = cell.rounded || rounded
+}
+
+// Horizontal adds a line length cells long going right from row, col
+func (l *Lines) Horizontal(row int, col int, length int, style int) *Lines {
+	weight, _ := styleWeights(style)
+	for i := 0; i < length; i++ {
+		if i > 0 || length == 1 {
+			l.add(row, col+i, 3, weight, style == LineStyleRounded)
+		}
+		if i < length-1 || length == 1 {
+			l.add(row, col+i, 1, weight, style == LineStyleRounded)
+		}
+	}
+	return l
+}
+
+// Vertical adds a line length cells long going down from row, col
+func (l *Lines) Vertical(row int, col int, length int, style int) *Lines {
+	_, weight := styleWeights(style)
+	for i := 0; i < length; i++ {
+		if i > 0 || length == 1 {
+			l.add(row+i, col, 0, weight, style == LineStyleRounded)
+		}
+		if i < length-1 || length == 1 {
+			l.add(row+i, col, 2, weight, style == LineStyleRounded)
+		}
+	}
+	return l
+}
+
+// Box adds the border of a box rows by cols with its top left corner at row, col
+func (l *Lines) Box(row int, col int, rows int, cols int, style int) *Lines {
+	l.Horizontal(row, col, cols, style)
+	l.Horizontal(row+rows-1, col, cols, style)
+	l.Vertical(row, col, rows, style)
+	l.Vertical(row, col+cols-1, rows, style)
+	return l
+}
+
+// Rune returns the character drawn at row, col, or a space if no line passes through it
+func (l *Lines) Rune(row int, col int) rune {
+	cell, ok := l.cells[[2]int{row, col}]
+	if !ok {
+		return ' '
+	}
+	return joinRune(string(cell.weights), cell.rounded)
+}
+
+// Draw draws the lines in the current colors.  Cells off the screen are left out, and the lines are drawn
+// with ASCII when the screen cannot show Unicode.
+func (l *Lines) Draw() {
+	rows, cols, err := screen.Size()
+	if err != nil {
+		return
+	}
+	var snap *Snapshot
+	if l.Merge {
+		snap, _ = screen.Capture()
+	}
+	ascii := !canDrawUnicode()
+	keys := make([][2]int, 0, len(l.cells))
+	for key := range l.cells {
+		if key[0] >= 0 && key[0] < rows && key[1] >= 0 && key[1] < cols {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1]
+	})
+	var sb strings.Builder
+	for i, key := range keys {
+		cell := l.cells[key]
+		weights, rounded := cell.weights, cell.rounded
+		if snap != nil && key[0] >= 0 && key[0] < snap.Rows && key[1] >= 0 && key[1] < snap.Cols {
+			weights, rounded = mergeWeights(weights, rounded, snap.Cells[key[0]][key[1]].Char)
+		}
+		if ascii {
+			weights = asciiWeights(weights)
+		}
+		if i == 0 || key[0] != keys[i-1][0] || key[1] != keys[i-1][1]+1 {
+			Print(sb.String())
+			sb.Reset()
+			Locate(key[0], key[1])
+		}
+		sb.WriteRune(joinRune(string(weights), rounded))
+	}
+	Print(sb.String())
+}
+
+// asciiWeights returns weights with each line drawn in ASCII
+func asciiWeights(weights []byte) []byte {
+	ascii := []byte(string(weights))
+	for i := range ascii {
+		if ascii[i] != lineNone {
+			ascii[i] = lineASCII
+		}
+	}
+	return ascii
+}
+
+// mergeWeights adds the lines of the box drawing character on the screen to the directions weights has no line in
+func mergeWeights(weights []byte, rounded bool, on rune) ([]byte, bool) {
+	existing, ok := boxRunes[on]
+	if corner, isRounded := roundedRunes[on]; isRounded {
+		existing, ok, rounded = corner, true, true
+	}
+	if !ok {
+		return weights, rounded
+	}
+	merged := []byte(string(weights))
+	for i := range merged {
+		if merged[i] == lineNone {
+			merged[i] = existing[i]
+		}
+	}
+	return merged, rounded
+}
+
+// DrawBox draws the border of a box rows by cols with its top left corner at row, col, joining it to lines
+// already on the screen
+func DrawBox(row int, col int, rows int, cols int, style int) {
+	lines := NewLines().Box(row, col, rows, cols, style)
+	lines.Merge = true
+	lines.Draw()
+}
+
+// DrawHorizontalLine draws a line length cells long going right from row, col, joining it to lines it crosses
+func DrawHorizontalLine(row int, col int, length int, style int) {
+	lines := NewLines().Horizontal(row, col, length, style)
+	lines.Merge = true
+	lines.Draw()
+}
+
+// DrawVerticalLine draws a line length cells long going down from row, col, joining it to lines it crosses
+func DrawVerticalLine(row int, col int, length int, style int) {
+	lines := NewLines().Vertical(row, col, length, style)
+	lines.Merge = true
+	lines.Draw()
+}
+
+// DrawShadow darkens the area one row below and two columns right of a box rows by cols at row, col,
+// leaving the characters there.  The current colors are kept.
+func DrawShadow(row int, col int, rows int, cols int) {
+	snap, _ := screen.Capture()
+	fg, bg := GetColor()
+	SetColor(ColorGray, ColorBlack)
+	shade := func(r int, c int, n int) {
+		var sb strings.Builder
+		for i := 0; i < n; i++ {
+			ch := ' '
+			if snap != nil && r >= 0 && r < snap.Rows && c+i >= 0 && c+i < snap.Cols {
+				ch = snap.Cells[r][c+i].Char
+			}
+			sb.WriteRune(ch)
+		}
+		Locate(r, c)
+		Print(sb.String())
+	}
+	for r := row + 1; r < row+rows; r++ {
+		shade(r, col+cols, 2)
+	}
+	shade(row+rows, col+2, cols)
+	SetColor(fg, bg)
+}
@@ -0,0 +1,284 @@
+package cons
+
+import (
+	"fmt"
+	"lib/fixed"
+	"strings"
+)
+
+// calculatorMemory is the calculator memory, kept between popups like a desk calculator
+var calculatorMemory fixed.Fixed
+
+// calculatorTapeLines is the number of tape lines shown above the display
+const calculatorTapeLines = 8
+
+// calculatorDigits is the most whole number digits an entry may have, so it fits in a fixed.Fixed
+const calculatorDigits = 14
+
+// calculator evaluates keys like a desk calculator: each operator applies the one before it to the running total
+type calculator struct {
+	total    fixed.Fixed
+	pending  byte
+	entry    string
+	tape     []string
+	decimals int
+	message  string
+	replace  bool
+}
+
+// CurrencyInputField makes a field a currency field.  F4 or Alt+Down opens a calculator starting with the
+// field value and Enter writes the result back with decimals places.
+func CurrencyInputField(field *InputField, decimals int) {
+	field.popup = func(field *InputField) (string, bool, error) {
+		value, err := fixed.FromString(strings.TrimSpace(field.value))
+		if err != nil {
+			value = fixed.Fixed{}
+		}
+		result, ok, err := Calculate(field.row+1, field.col, value, decimals)
+		if !ok || err != nil {
+			return "", false, err
+		}
+		return result.ToString(decimals, 0, 0), true, nil
+	}
+}
+
+// Calculate shows a popup calculator at row, col, or as close as fits, starting with value.  Digits, the
+// decimal point, + - * / and % work like a desk calculator with a running tape.  = totals, Enter totals and
+// returns the result rounded to decimals places, and Esc cancels.  Backspace deletes a digit, Del clears the
+// entry and C clears everything.  F5 clears memory, F6 recalls it, F7 adds to it, F8 subtracts from it and
+// F9 changes the sign of the entry.  ErrInterrupted is returned for Control+C.
+func Calculate(row int, col int, value fixed.Fixed, decimals int) (fixed.Fixed, bool, error) {
+	defer HoldScreen()()
+	const rows, cols = calculatorTapeLines + 5, 30
+	screenRows, screenCols, _ := WindowSizeErr()
+	if row+rows > screenRows {
+		row = max(0, row-rows-1)
+	}
+	col = max(0, min(col, screenCols-cols))
+	w := NewWindow(row, col, rows, cols, LineStyleSingle, ColorBlack, ColorWhite)
+	w.Title = "Calculator"
+	w.Open()
+	defer w.Close()
+	c := &calculator{decimals: decimals}
+	if value.Compare(fixed.Fixed{}) != 0 {
+		c.entry = entryText(value)
+	}
+	for {
+		c.draw(w)
+		key, err := GetKeyErr()
+		if err != nil {
+			return value, false, err
+		}
+		switch {
+		case key.Key == KeyEnter:
+			c.press('=')
+			if c.message != "" {
+				Beep()
+				break
+			}
+			result := c.total
+			result.Round(max(0, min(decimals, 4)))
+			return result, true, nil
+		case key.Key == KeyEscape:
+			return value, false, nil
+		case key == Ctrl('C'):
+			if interrupted() {
+				return value, false, ErrInterrupted
+			}
+		case key.Key == KeyBackspace:
+			c.press('\b')
+		case key.Key == KeyDel:
+			c.press('E')
+		case key.Key >= KeyF5 && key.Key <= KeyF9:
+			c.press("LRPMN"[key.Key-KeyF5])
+		case isPrintable(key) && strings.IndexByte("0123456789.+-*/%=cC", key.Key) >= 0:
+			c.press(key.Key)
+		default:
+			Beep()
+		}
+	}
+}
+
+// current returns the number being entered, or the running total if nothing has been entered
+func (c *calculator) current() fixed.Fixed {
+	if c.entry == "" {
+		return c.total
+	}
+	value, err := fixed.FromString(c.entry)
+	if err != nil {
+		return c.total
+	}
+	return value
+}
+
+// entryText formats a number as it would be typed, without trailing zero decimals
+func entryText(value fixed.Fixed) string {
+	text := value.ToString(4, 0, 0)
+	return strings.TrimSuffix(strings.TrimRight(text, "0"), ".")
+}
+
+// format formats a number rounded to the calculator's decimals for the display and tape
+func (c *calculator) format(value fixed.Fixed) string {
+	decimals := max(0, min(c.decimals, 4))
+	value.Round(decimals)
+	return value.ToString(decimals, ',', 0)
+}
+
+// apply applies the pending operator to the running total and value
+func (c *calculator) apply(value fixed.Fixed) bool {
+	switch c.pending {
+	case '+':
+		c.total = c.total.Add(value)
+	case '-':
+		c.total = c.total.Sub(value)
+	case '*':
+		c.total = c.total.Mul(value)
+	case '/':
+		if value.Compare(fixed.Fixed{}) == 0 {
+			c.message = "Divide by zero"
+			c.total, c.pending, c.entry = fixed.Fixed{}, 0, ""
+			return false
+		}
+		c.total = c.total.Div(value)
+	default:
+		c.total = value
+	}
+	return true
+}
+
+// record adds a line to the tape
+func (c *calculator) record(value fixed.Fixed, mark string) {
+	c.tape = append(c.tape, fmt.Sprintf("%s %s", c.format(value), mark))
+}
+
+// press handles a calculator key: a digit, '.', an operator, '%', '=', '\b' (backspace), 'E' (clear entry),
+// 'c' or 'C' (clear all), 'L' (clear memory), 'R' (recall memory), 'P' (memory plus), 'M' (memory minus)
+// or 'N' (negate)
+func (c *calculator) press(key byte) {
+	c.message = ""
+	replace := c.replace
+	c.replace = false
+	switch key {
+	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', '.':
+		if replace {
+			c.entry = ""
+		}
+		whole := strings.TrimPrefix(c.entry, "-")
+		decimal := strings.Contains(whole, ".")
+		if key == '.' && decimal || len(c.entry) >= 15 || key != '.' && !decimal && len(whole) >= calculatorDigits {
+			Beep()
+			return
+		}
+		c.entry += string(key)
+	case '\b':
+		if c.entry != "" && !replace {
+			c.entry = c.entry[:len(c.entry)-1]
+		}
+	case 'E':
+		c.entry = ""
+	case 'c', 'C':
+		c.total, c.pending, c.entry = fixed.Fixed{}, 0, ""
+		c.tape = append(c.tape, "C")
+	case 'N':
+		if strings.HasPrefix(c.entry, "-") {
+			c.entry = c.entry[1:]
+		} else if c.entry != "" {
+			c.entry = "-" + c.entry
+		} else {
+			zero := fixed.Fixed{}
+			c.total = zero.Sub(c.total)
+		}
+	case 'L':
+		calculatorMemory = fixed.Fixed{}
+	case 'R':
+		c.entry = entryText(calculatorMemory)
+		c.replace = true
+	case 'P', 'M':
+		value := c.current()
+		if key == 'P' {
+			calculatorMemory = calculatorMemory.Add(value)
+			c.record(value, "M+")
+		} else {
+			calculatorMemory = calculatorMemory.Sub(value)
+			c.record(value, "M-")
+		}
+		c.replace = true
+	case '+', '-', '*', '/':
+		value := c.current()
+		if c.entry != "" || c.pending == 0 {
+			c.record(value, string(key))
+			if !c.apply(value) {
+				return
+			}
+		} else {
+			c.tape[len(c.tape)-1] = fmt.Sprintf("%s %c", c.format(c.total), key)
+		}
+		c.pending, c.entry = key, ""
+	case '%':
+		value := c.current()
+		c.record(value, "%")
+		if c.pending == '+' || c.pending == '-' {
+			value = c.total.Mul(value)
+		}
+		percent := value.Div(fixed.FromInt(100))
+		if c.apply(percent) {
+			c.record(c.total, "=")
+		}
+		c.pending, c.entry = 0, ""
+	case '=':
+		if c.pending == 0 && c.entry == "" {
+			return
+		}
+		value := c.current()
+		c.record(value, "=")
+		if c.apply(value) {
+			c.record(c.total, "T")
+		}
+		c.pending, c.entry = 0, ""
+	}
+}
+
+// draw draws the tape, the display and the memory indicator
+func (c *calculator) draw(w *Window) {
+	top, left, _, cols := w.Inner()
+	SetColor(w.Foreground, w.Background)
+	tape := c.tape[max(0, len(c.tape)-calculatorTapeLines):]
+	for i := 0; i < calculatorTapeLines; i++ {
+		Locate(top+i, left)
+		line := ""
+		if i < len(tape) {
+			line = tape[i]
+		}
+		Print(fitText(strings.Repeat(" ", max(0, cols-1-len([]rune(line))))+line, cols))
+	}
+	Locate(top+calculatorTapeLines, left-1)
+	box := GetBoxChars(w.Border)
+	Print(box.LeftJoin, strings.Repeat(box.Horizontal, cols), box.RightJoin)
+	indicator := " "
+	if calculatorMemory.Compare(fixed.Fixed{}) != 0 {
+		indicator = "M"
+	}
+	display := c.entry
+	if display == "" {
+		display = c.format(c.total)
+	}
+	if c.message != "" {
+		display = c.message
+	}
+	if c.pending != 0 {
+		indicator += string(c.pending)
+	} else {
+		indicator += " "
+	}
+	SetColor(ColorBrightWhite, ColorBlack)
+	Locate(top+calculatorTapeLines+1, left)
+	Print(indicator, fitText(strings.Repeat(" ", max(0, cols-3-len([]rune(display))))+display, cols-3), " ")
+	SetColor(w.Foreground, w.Background)
+	Locate(top+calculatorTapeLines+2, left)
+	legend := "F5 MC F6 MR F7 M+ F8 M- F9 ±"
+	if !canDrawUnicode() {
+		legend = "F5MC F6MR F7M+ F8M- F9+/-"
+	}
+	Print(fitText(legend, cols))
+	Locate(top+calculatorTapeLines+1, left+cols-2)
+}
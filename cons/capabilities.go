@@ -0,0 +1,166 @@
+package cons
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// ColorsTrueColor is the number of colors of a terminal with 24 bit color
+const ColorsTrueColor = 1 << 24
+
+// Capabilities describes what the terminal can show, so output can degrade gracefully, i.e. drawing boxes with
+// ASCII when the locale is not UTF-8.
+type Capabilities struct {
+	// Term is the terminal type from $TERM, or empty for the Windows console
+	Term string
+	// Colors is the number of colors: 0, 8, 16, 256 or ColorsTrueColor
+	Colors int
+	// UTF8 is true if Unicode characters such as box drawing can be shown
+	UTF8 bool
+	// LineDrawing is true if lines can be drawn, with Unicode or the terminal's alternate character set
+	LineDrawing bool
+	// Title is true if the window title can be set
+	Title bool
+	// Mouse is true if the terminal can report mouse events
+	Mouse bool
+	// Terminfo is the entry the capabilities came from, or nil if there is none
+	Terminfo *Terminfo
+}
+
+var (
+	capabilitiesMu    sync.Mutex
+	capabilitiesKnown bool
+	capabilities      Capabilities
+)
+
+// GetCapabilities returns the capabilities of the terminal, detecting them the first time
+func GetCapabilities() Capabilities {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	if !capabilitiesKnown {
+		capabilities, capabilitiesKnown = DetectCapabilities(), true
+	}
+	return capabilities
+}
+
+// SetCapabilities replaces the detected capabilities, i.e. to force ASCII output.  It may be called from any
+// goroutine.
+func SetCapabilities(c Capabilities) {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	capabilities, capabilitiesKnown = c, true
+}
+
+// DetectCapabilities detects the capabilities of the console.  On the Windows console they are fixed.  Elsewhere
+// the terminfo entry for $TERM is read, or a built-in entry used for common terminals, and COLORTERM and the
+// locale are checked.
+func DetectCapabilities() Capabilities {
+	if c, ok := sysCapabilities(); ok {
+		return c
+	}
+	return detectCapabilities(os.Getenv, LoadTerminfo)
+}
+
+// detectCapabilities detects terminal capabilities from the environment and terminfo
+func detectCapabilities(getenv func(string) string, load func(string) (*Terminfo, error)) Capabilities {
+	c := Capabilities{Term: getenv("TERM"), UTF8: isUTF8Locale(getenv)}
+	if c.Term == "" || c.Term == "dumb" {
+		return c
+	}
+	ti, err := load(c.Term)
+	if err != nil {
+		ti = builtinTerminfo(c.Term)
+	}
+	c.Terminfo = ti
+	if ti != nil {
+		if ti.Strings["setaf"] != "" || ti.Strings["setf"] != "" {
+			c.Colors = ti.Numbers["colors"]
+		}
+		if ti.Bools["Tc"] || ti.Bools["RGB"] || ti.Numbers["RGB"] > 0 || ti.Strings["RGB"] != "" {
+			c.Colors = ColorsTrueColor
+		}
+		c.LineDrawing = c.UTF8 || ti.Strings["acsc"] != ""
+		c.Title = ti.Strings["tsl"] != "" || ti.Bools["XT"]
+		c.Mouse = ti.Strings["kmous"] != "" || ti.Strings["XM"] != ""
+	}
+	if strings.Contains(c.Term, "256color") {
+		c.Colors = max(c.Colors, 256)
+	}
+	switch strings.ToLower(getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		c.Colors = ColorsTrueColor
+	}
+	return c
+}
+
+// isUTF8Locale returns true if the locale from LC_ALL, LC_CTYPE or LANG uses UTF-8
+func isUTF8Locale(getenv func(string) string) bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := getenv(name); locale != "" {
+			locale = strings.ToLower(locale)
+			return strings.Contains(locale, "utf-8") || strings.Contains(locale, "utf8")
+		}
+	}
+	return false
+}
+
+// builtinTerminfo returns a built-in entry for common terminals whose terminfo cannot be read, or nil
+func builtinTerminfo(term string) *Terminfo {
+	base := term
+	for _, prefix := range []string{"xterm", "rxvt", "alacritty", "kitty", "screen", "tmux", "linux", "vt100", "vt102", "vt220"} {
+		if strings.HasPrefix(term, prefix) {
+			base = prefix
+			break
+		}
+	}
+	const acsc = "``aaffggiijjkkllmmnnooppqqrrssttuuvvwwxxyyzz{{||}}~~"
+	ansi := map[string]string{
+		"clear": "\x1b[H\x1b[2J", "cup": "\x1b[%i%p1%d;%p2%dH", "el": "\x1b[K", "sgr0": "\x1b[m",
+		"bold": "\x1b[1m", "rev": "\x1b[7m", "smul": "\x1b[4m", "acsc": acsc, "smacs": "\x1b(0", "rmacs": "\x1b(B",
+	}
+	colors := func() {
+		ansi["setaf"] = "\x1b[3%p1%dm"
+		ansi["setab"] = "\x1b[4%p1%dm"
+		ansi["op"] = "\x1b[39;49m"
+	}
+	ti := &Terminfo{Names: []string{term}, Bools: map[string]bool{"am": true}, Numbers: map[string]int{"cols": 80, "lines": 24},
+		Strings: ansi}
+	switch base {
+	case "xterm", "rxvt", "alacritty", "kitty":
+		colors()
+		ti.Numbers["colors"] = 8
+		ti.Bools["XT"] = true
+		ansi["kmous"] = "\x1b[M"
+		ansi["civis"] = "\x1b[?25l"
+		ansi["cnorm"] = "\x1b[?25h"
+	case "screen", "tmux":
+		colors()
+		ti.Numbers["colors"] = 8
+		ti.Bools["hs"] = true
+		ansi["tsl"] = "\x1b_"
+		ansi["fsl"] = "\x1b\\"
+		ansi["kmous"] = "\x1b[M"
+	case "linux":
+		colors()
+		ti.Numbers["colors"] = 8
+		ansi["acsc"] = "++,,--..00__" + acsc
+		ansi["smacs"] = "\x1b[11m"
+		ansi["rmacs"] = "\x1b[10m"
+	case "vt100", "vt102", "vt220":
+	default:
+		return nil
+	}
+	return ti
+}
+
+// canDrawUnicode returns true if output drawn on the screen can use Unicode box drawing characters.  Screens
+// other than a console terminal, such as a VirtualScreen, always can.
+func canDrawUnicode() bool {
+	return !isConsole() || !IsTerminal() || GetCapabilities().UTF8
+}
+
+// canDrawColor returns true if output drawn on the screen can change colors
+func canDrawColor() bool {
+	return !isConsole() || !IsTerminal() || GetCapabilities().Colors > 0
+}
@@ -1,55 +1,12 @@
 package cons
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"runtime"
 	"strconv"
-	"strings"
-	"syscall"
-	"unicode/utf16"
-	"unsafe"
-)
-
-var (
-	kernel32DLL                 = syscall.NewLazyDLL("kernel32.dll")
-	wGetConsoleTitle            = kernel32DLL.NewProc("GetConsoleTitleW")
-	wSetConsoleTitle            = kernel32DLL.NewProc("SetConsoleTitleW")
-	wGetConsoleDisplayMode      = kernel32DLL.NewProc("GetConsoleDisplayMode")
-	wSetConsoleDisplayMode      = kernel32DLL.NewProc("SetConsoleDisplayMode")
-	wSetConsoleTextAttribute    = kernel32DLL.NewProc("SetConsoleTextAttribute")
-	wGetConsoleScreenBufferInfo = kernel32DLL.NewProc("GetConsoleScreenBufferInfo")
-	wSetConsoleCursorPosition   = kernel32DLL.NewProc("SetConsoleCursorPosition")
-	wSetConsoleWindowInfo       = kernel32DLL.NewProc("SetConsoleWindowInfo")
-	wSetConsoleScreenBufferSize = kernel32DLL.NewProc("SetConsoleScreenBufferSize")
-	wFillConsoleOutputCharacter = kernel32DLL.NewProc("FillConsoleOutputCharacterA")
-	wFillConsoleOutputAttribute = kernel32DLL.NewProc("FillConsoleOutputAttribute")
-	wSetConsoleCP               = kernel32DLL.NewProc("SetConsoleCP")
-	wSetConsoleOutputCP         = kernel32DLL.NewProc("SetConsoleOutputCP")
-	wSetConsoleMode             = kernel32DLL.NewProc("SetConsoleMode")
-	wReadConsoleInput           = kernel32DLL.NewProc("ReadConsoleInputA")
-	wPeekConsoleInput           = kernel32DLL.NewProc("PeekConsoleInput")
-	wGetStdHandle               = kernel32DLL.NewProc("GetStdHandle")
 )
 
-const (
-	wVkShift   = 0x10
-	wVkControl = 0x11
-	wVkMenu    = 0x12
-	wVkCapital = 0x14
-	wVkLWin    = 0x5b
-	wVkRWin    = 0x5c
-
-	wCapsLockOn       = 0x80
-	wEnhancedKey      = 0x100
-	wLeftAltPressed   = 0x2
-	wLeftCtrlPressed  = 0x8
-	wNumlockOn        = 0x20
-	wRightAltPressed  = 0x1
-	wRightCtrlPressed = 0x4
-	wScrollLockOn     = 0x40
-	wShiftPressed     = 0x10
-)
 const (
 	// KeyControlC is Control+C
 	KeyControlC = 3
@@ -131,6 +88,8 @@ const (
 	KeyF23 = 165
 	// KeyF24 is the F24 key
 	KeyF24 = 166
+	// KeyRune is a character outside ASCII, held in the Rune of the key event
+	KeyRune = 167
 )
 
 const (
@@ -140,6 +99,16 @@ const (
 	LineStyleSingle = 1
 	// LineStyleDouble draws a double line3
 	LineStyleDouble = 2
+	// LineStyleHeavy draws a heavy line
+	LineStyleHeavy = 3
+	// LineStyleRounded draws a single line with rounded corners
+	LineStyleRounded = 4
+	// LineStyleASCII draws lines with - | and +
+	LineStyleASCII = 5
+	// LineStyleDoubleHorizontal draws double horizontal and single vertical lines
+	LineStyleDoubleHorizontal = 6
+	// LineStyleDoubleVertical draws single horizontal and double vertical lines
+	LineStyleDoubleVertical = 7
 )
 const (
 	// KeyCapsLock is the caps lock modifier
@@ -158,6 +127,8 @@ type KeyEvent struct {
 	Key uint8
 	// Modifier is the bit mask of modifiers that were down when the key was pressed
 	Modifier int8
+	// Rune is the character typed when Key is KeyRune
+	Rune rune
 }
 
 const (
@@ -197,81 +168,76 @@ const (
 	ColorBrightWhite = ColorWhite | ColorBright
 )
 
-type (
-	// wCharInfo is the windows CHAR_INFO structure
-	wCharInfo struct {
-		UnicodeChar uint16
-		Attributes  uint16
-	}
+// ErrNoConsole is returned by console operations when the program is not attached to a console
+var ErrNoConsole = errors.New("not a console")
 
-	// wConsoleCursorInfo is the windows CONSOLE_CURSOR_INFO
-	wConsoleCursorInfo struct {
-		Size    uint32
-		Visible int32
-	}
+// ConsoleError records a failed console operation and the system error that caused it
+type ConsoleError struct {
+	// Op is the name of the operation, i.e. "Locate"
+	Op string
+	// Err is the underlying system error
+	Err error
+}
 
-	// wConsoleScreenBufferInfo is the windows CONSOLE_SCREEN_BUFFER_INFO
-	wConsoleScreenBufferInfo struct {
-		Size              wCoord
-		CursorPosition    wCoord
-		Attributes        uint16
-		Window            wSmallRect
-		MaximumWindowSize wCoord
-	}
+func (e *ConsoleError) Error() string {
+	return "cons: " + e.Op + ": " + e.Err.Error()
+}
 
-	// wCoord is the windows COORD structure
-	wCoord struct {
-		X int16
-		Y int16
-	}
+// Unwrap returns the underlying system error
+func (e *ConsoleError) Unwrap() error {
+	return e.Err
+}
 
-	// wSmallRect is the windows SMALL_RECT structure
-	wSmallRect struct {
-		Left   int16
-		Top    int16
-		Right  int16
-		Bottom int16
+// opError wraps err with the operation name, or returns nil if err is nil
+func opError(op string, err error) error {
+	if err == nil {
+		return nil
 	}
+	return &ConsoleError{Op: op, Err: err}
+}
 
-	// wInputRecord is the windows INPUT_RECORD structure
-	wInputRecord struct {
-		EventType uint16
-		KeyEvent  wKeyEventRecord
-	}
+var doBeep = false
+var original = sysSaveState()
 
-	// wKeyEventRecord is the windows KEY_EVENT_RECORD structure
-	wKeyEventRecord struct {
-		KeyDown         int32
-		RepeatCount     uint16
-		VirtualKeyCode  uint16
-		VirtualScanCode uint16
-		ASCIIChar       [2]uint8
-		ControlKeyState uint32
-	}
+// IsTerminal returns true if stdin and stdout are attached to a console.  When false, console
+// operations fail with ErrNoConsole or a system error and callers should fall back to plain text.
+func IsTerminal() bool {
+	return sysIsTerminal()
+}
 
-	// wWindowBufferSize is the windows WINDOW_BUFFER_SIZEW structure
-	wWindowBufferSize struct {
-		Size wCoord
-	}
-)
+// Restore puts back the console mode, colors and cursor the program started with
+func Restore() {
+	RestoreErr()
+}
 
-var doBeep = false
+// RestoreErr puts back the console mode, colors and cursor the program started with
+func RestoreErr() error {
+	return opError("Restore", sysRestore(original))
+}
 
 // SetTitle sets the console window title
 func SetTitle(title string) {
+	SetTitleErr(title)
+}
+
+// SetTitleErr sets the console window title
+func SetTitleErr(title string) error {
 	if len(title) > 240 {
 		title = title[0:240]
 	}
-	wSetConsoleTitle.Call(uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(title))))
+	return opError("SetTitle", sysSetTitle(title))
 }
 
 // GetTitle gets the console window title
 func GetTitle() string {
-	arr := make([]uint16, 257)
-	var arrSize = uint32(len(arr) - 1)
+	title, _ := GetTitleErr()
+	return title
+}
 
-	wGetConsoleTitle.Call(uintptr(unsafe.Pointer(&arr[0])), uintptr(arrSize))
-	return strings.TrimRight(string(utf16.Decode(arr)), " \000")
+// GetTitleErr gets the console window title
+func GetTitleErr() (string, error) {
+	title, err := sysGetTitle()
+	return title, opError("GetTitle", err)
 }
 
 // GetStdOut gets the stdout windows handle
@@ -286,251 +252,214 @@ func GetStdIn() uintptr {
 
 // IsFullScreen returns true if full screen or false if windowed
 func IsFullScreen() bool {
-	var mode uint32
-	wGetConsoleDisplayMode.Call(uintptr(unsafe.Pointer(&mode)))
-	return (mode == 1) // 1=full screen, 2=windowed
+	full, _ := IsFullScreenErr()
+	return full
+}
+
+// IsFullScreenErr returns true if full screen or false if windowed
+func IsFullScreenErr() (bool, error) {
+	full, err := sysIsFullScreen()
+	return full, opError("IsFullScreen", err)
 }
 
 // SetFullScreen changes to full screen for 32-bit applications.  Does not work for 64bit applications
 func SetFullScreen() {
-	var c wCoord
-	if runtime.GOARCH == "amd64" {
-		return
-	}
-	stdout := GetStdOut()
-	fmt.Println(stdout)
-	a, b, d := wSetConsoleDisplayMode.Call(stdout, 1, uintptr(unsafe.Pointer(&c)))
-	fmt.Println("Set FullScreen", a, b, d, c.X, c.Y)
+	SetFullScreenErr()
+}
+
+// SetFullScreenErr changes to full screen for 32-bit applications.  64bit applications get errors.ErrUnsupported.
+func SetFullScreenErr() error {
+	return opError("SetFullScreen", sysSetDisplayMode(1))
 }
 
 // SetWindowed changes to windowed mode for 32-bit applications.  Does not work for 64bit applications
 func SetWindowed() {
-	var c wCoord
-	if runtime.GOARCH == "amd64" {
-		return
-	}
+	SetWindowedErr()
+}
 
-	stdout := GetStdOut()
-	fmt.Println(stdout)
-	a, b, d := wSetConsoleDisplayMode.Call(stdout, 2, uintptr(unsafe.Pointer(&c)))
-	fmt.Println("Set Windowed", a, b, d, c.X, c.Y)
+// SetWindowedErr changes to windowed mode for 32-bit applications.  64bit applications get errors.ErrUnsupported.
+func SetWindowedErr() error {
+	return opError("SetWindowed", sysSetDisplayMode(2))
 }
 
 // SetColor sets the foreground and background colors for subsequent output
 func SetColor(foreground int8, background int8) {
-	stdout := GetStdOut()
-	wSetConsoleTextAttribute.Call(stdout, uintptr(foreground|(background<<4)))
+	SetColorErr(foreground, background)
+}
+
+// SetColorErr sets the foreground and background colors for subsequent output
+func SetColorErr(foreground int8, background int8) error {
+	return opError("SetColor", screen.SetColor(foreground, background))
+}
+
+// GetColor returns the current foreground and background colors
+func GetColor() (foreground int8, background int8) {
+	foreground, background, _ = GetColorErr()
+	return foreground, background
+}
+
+// GetColorErr returns the current foreground and background colors
+func GetColorErr() (foreground int8, background int8, err error) {
+	foreground, background, err = screen.Color()
+	return foreground, background, opError("GetColor", err)
 }
 
 // Rows returns the number of rows on the console window
 func Rows() int {
-	var info wConsoleScreenBufferInfo
-	stdout := GetStdOut()
-	wGetConsoleScreenBufferInfo.Call(stdout, uintptr(unsafe.Pointer(&info)))
-	return int(info.Window.Bottom - info.Window.Top + 1)
+	rows, _, _ := WindowSizeErr()
+	return rows
 }
 
 // BufferRows returns the number of buffer rows on the console window
 func BufferRows() int {
-	var info wConsoleScreenBufferInfo
-	stdout := GetStdOut()
-	wGetConsoleScreenBufferInfo.Call(stdout, uintptr(unsafe.Pointer(&info)))
-	return int(info.Size.Y)
+	rows, _, _ := BufferSizeErr()
+	return rows
 }
 
 // Cols returns the number of columns on the console screen
 func Cols() int {
-	var info wConsoleScreenBufferInfo
-	stdout := GetStdOut()
-	wGetConsoleScreenBufferInfo.Call(stdout, uintptr(unsafe.Pointer(&info)))
-	return int(info.Window.Right - info.Window.Left + 1)
+	_, cols, _ := WindowSizeErr()
+	return cols
 }
 
 // BufferCols returns the number of buffer columns on the console screen
 func BufferCols() int {
-	var info wConsoleScreenBufferInfo
-	stdout := GetStdOut()
-	wGetConsoleScreenBufferInfo.Call(stdout, uintptr(unsafe.Pointer(&info)))
-	return int(info.Size.X)
+	_, cols, _ := BufferSizeErr()
+	return cols
+}
+
+// WindowSizeErr returns the number of rows and columns on the console window.  The row reserved by a
+// status bar is not counted.
+func WindowSizeErr() (rows int, cols int, err error) {
+	rows, cols, err = screen.Size()
+	if err == nil && statusBar.Load() != nil {
+		rows--
+	}
+	return rows, cols, opError("WindowSize", err)
+}
+
+// BufferSizeErr returns the number of buffer rows and columns of the console.  Other screens have no buffer
+// beyond their size.
+func BufferSizeErr() (rows int, cols int, err error) {
+	if isConsole() {
+		rows, cols, err = sysBufferSize()
+	} else {
+		rows, cols, err = screen.Size()
+	}
+	return rows, cols, opError("BufferSize", err)
 }
 
 // Row returns the current screen row the cursor is on from 0 to Rows-1
 func Row() int {
-	var info wConsoleScreenBufferInfo
-	stdout := GetStdOut()
-	wGetConsoleScreenBufferInfo.Call(stdout, uintptr(unsafe.Pointer(&info)))
-	return int(info.CursorPosition.Y)
+	row, _, _ := CursorErr()
+	return row
 }
 
 // Col returns the current screen column the cursor is on from 0 to Cols-1
 func Col() int {
-	var info wConsoleScreenBufferInfo
-	stdout := GetStdOut()
-	wGetConsoleScreenBufferInfo.Call(stdout, uintptr(unsafe.Pointer(&info)))
-	return int(info.CursorPosition.X)
+	_, col, _ := CursorErr()
+	return col
+}
+
+// CursorErr returns the current screen row and column of the cursor
+func CursorErr() (row int, col int, err error) {
+	row, col, err = screen.Cursor()
+	return row, col, opError("Cursor", err)
 }
 
 // Locate positions the cursor to a row and column.  valid values are 0 to Rows-1 and 0 to Cols-1
 func Locate(row int, col int) {
-	var coord wCoord
-	coord.X = int16(col)
-	coord.Y = int16(row)
+	LocateErr(row, col)
+}
 
-	stdout := GetStdOut()
-	wSetConsoleCursorPosition.Call(stdout, coordToUintptr(coord))
+// LocateErr positions the cursor to a row and column.  valid values are 0 to Rows-1 and 0 to Cols-1
+func LocateErr(row int, col int) error {
+	return opError("Locate", screen.Locate(row, col))
 }
 
 // SetWindowSize sets the console window rows and columns and matches the buffer to it.
 func SetWindowSize(rows int, cols int) {
-	var rect wSmallRect
-	rect.Top = 0
-	rect.Left = 0
-	rect.Bottom = int16(rows - 1)
-	rect.Right = int16(cols - 1)
-	stdout := GetStdOut()
-	wSetConsoleWindowInfo.Call(stdout, 1, uintptr(unsafe.Pointer(&rect)))
-
-	var coord wCoord
-	coord.X = int16(cols)
-	coord.Y = int16(rows)
-	wSetConsoleScreenBufferSize.Call(stdout, coordToUintptr(coord))
+	SetWindowSizeErr(rows, cols)
 }
 
-func coordToUintptr(coord wCoord) uintptr {
-	return uintptr(*((*uint32)(unsafe.Pointer(&coord))))
+// SetWindowSizeErr sets the console window rows and columns and matches the buffer to it.
+func SetWindowSizeErr(rows int, cols int) error {
+	return opError("SetWindowSize", screen.Resize(rows, cols))
 }
 
 // SetWindowAndBufferSize sets the window rows/cols and buffer rows/cols.  buffer must be >= window size
 func SetWindowAndBufferSize(rows int, cols int, bufRows int, bufCols int) {
-	var rect wSmallRect
-	rect.Top = 0
-	rect.Left = 0
-	rect.Bottom = int16(rows - 1)
-	rect.Right = int16(cols - 1)
-	stdout := GetStdOut()
-	wSetConsoleWindowInfo.Call(stdout, 1, uintptr(unsafe.Pointer(&rect)))
+	SetWindowAndBufferSizeErr(rows, cols, bufRows, bufCols)
+}
 
-	var coord wCoord
-	coord.X = int16(bufCols)
-	coord.Y = int16(bufRows)
-	wSetConsoleScreenBufferSize.Call(stdout, coordToUintptr(coord))
+// SetWindowAndBufferSizeErr sets the window rows/cols and buffer rows/cols.  buffer must be >= window size.
+// Screens other than the console are resized to the window size.
+func SetWindowAndBufferSizeErr(rows int, cols int, bufRows int, bufCols int) error {
+	if !isConsole() {
+		return opError("SetWindowAndBufferSize", screen.Resize(rows, cols))
+	}
+	return opError("SetWindowAndBufferSize", sysSetWindowAndBufferSize(rows, cols, bufRows, bufCols))
 }
 
 // Cls clears the screen using the current foreground/background
 func Cls() {
-	var coordScreen wCoord
-	var cCharsWritten uint32
-	var csbi wConsoleScreenBufferInfo
-	var dwConSize uint32
-
-	// Get the number of character cells in the current buffer.
-	stdout := GetStdOut()
-	if err, _, _ := wGetConsoleScreenBufferInfo.Call(stdout, uintptr(unsafe.Pointer(&csbi))); err == 0 {
-		return
-	}
-
-	dwConSize = uint32(csbi.Size.X * csbi.Size.Y)
-
-	var c = uintptr(' ')
-	// Fill the entire screen with blanks.
-
-	if err, _, _ := wFillConsoleOutputCharacter.Call(stdout, c, uintptr(dwConSize), coordToUintptr(coordScreen), uintptr(unsafe.Pointer(&cCharsWritten))); err == 0 {
-		return
-	}
-
-	// Get the current text attribute.
-	if err, _, _ := wGetConsoleScreenBufferInfo.Call(stdout, uintptr(unsafe.Pointer(&csbi))); err == 0 {
-		return
-	}
+	ClsErr()
+}
 
-	// Set the buffer's attributes accordingly.
-	if err, _, _ := wFillConsoleOutputAttribute.Call(stdout,
-		uintptr(csbi.Attributes),
-		uintptr(dwConSize),
-		coordToUintptr(coordScreen),
-		uintptr(unsafe.Pointer(&cCharsWritten))); err == 0 {
-		return
+// ClsErr clears the screen using the current foreground/background.  A status bar is redrawn.
+func ClsErr() error {
+	err := screen.Clear()
+	if bar := statusBar.Load(); err == nil && bar != nil {
+		bar.draw()
 	}
-
-	// Put the cursor at its home coordinates.
-	wSetConsoleCursorPosition.Call(stdout, coordToUintptr(coordScreen))
-	wSetConsoleCP.Call(uintptr(65001))
-	wSetConsoleOutputCP.Call(uintptr(65001))
+	return opError("Cls", err)
 }
 
 // Center writes a string centered in the console and advances to the next line.
 func Center(value string) {
-	length := len([]rune(value))
-	col := (Cols() - length) / 2
-	Locate(Row(), col)
-	fmt.Println(value)
+	CenterErr(value)
 }
 
-// GetKey returns a keypress event
-func GetKey() KeyEvent {
-	var c uint8
-	var read uint32
-	var kc uint16
-	var rec wInputRecord
-	stdin := GetStdIn()
-	wSetConsoleMode.Call(stdin, uintptr(0))
-
-	for rec.EventType != 1 || (rec.EventType == 1 && rec.KeyEvent.KeyDown == 0) || c == 0 {
-		wReadConsoleInput.Call(stdin, uintptr(unsafe.Pointer(&rec)), uintptr(1), uintptr(unsafe.Pointer(&read)))
-		c = rec.KeyEvent.ASCIIChar[0]
-		switch rec.KeyEvent.VirtualKeyCode {
-		case wVkShift, wVkControl, wVkMenu, wVkCapital, wVkLWin, wVkRWin:
-			c = 0
-		}
-		kc = rec.KeyEvent.VirtualKeyCode
-		if kc >= 0x21 && kc <= 0x2f {
-			c = 1
-		}
-		if kc >= 0x70 && kc <= 0x87 {
-			c = 1
+// CenterErr writes a string centered in the console and advances to the next line.  The string is written
+// even if it cannot be centered, starting at the left edge when it is wider than the console.
+func CenterErr(value string) error {
+	length := len([]rune(value))
+	_, cols, err := screen.Size()
+	if err == nil {
+		var row int
+		row, _, err = screen.Cursor()
+		if err == nil {
+			err = screen.Locate(row, max(0, (cols-length)/2))
 		}
 	}
-	// Special Arrow handling
-	if kc >= 0x21 && kc <= 0x2f {
-		c = uint8(129 - 0x21 + kc)
-	}
-	if kc >= 0x70 && kc <= 0x87 {
-		c = uint8(143 - 0x70 + kc)
+	if _, printErr := fmt.Fprintln(screen, value); err == nil {
+		err = printErr
 	}
+	return opError("Center", err)
+}
 
-	mods := 0
-	if (rec.KeyEvent.ControlKeyState & wCapsLockOn) != 0 {
-		mods |= KeyCapsLock
-	}
-	if (rec.KeyEvent.ControlKeyState & (wLeftAltPressed | wRightAltPressed)) != 0 {
-		mods |= KeyAlt
-	}
-	if (rec.KeyEvent.ControlKeyState & (wLeftCtrlPressed | wRightCtrlPressed)) != 0 {
-		mods |= KeyControl
-	}
-	if (rec.KeyEvent.ControlKeyState & wShiftPressed) != 0 {
-		mods |= KeyShift
-	}
-	return KeyEvent{Key: uint8(c), Modifier: int8(mods)}
+// GetKey returns a keypress event
+func GetKey() KeyEvent {
+	key, _ := GetKeyErr()
+	return key
+}
+
+// GetKeyErr waits for and returns a keypress event
+func GetKeyErr() (KeyEvent, error) {
+	key, err := readKey(true)
+	return key, opError("GetKey", err)
 }
 
 // Inkey returns 0 key if no input available or a keypress if available.  Does not block or wait.
 func Inkey() KeyEvent {
-	var rec wInputRecord
-	var e = KeyEvent{0, 0}
-	var reads uint
-	stdin := GetStdIn()
-	for {
-		wPeekConsoleInput.Call(stdin, uintptr(unsafe.Pointer(&rec)), 1, uintptr(unsafe.Pointer(&reads)))
-		if reads > 0 && rec.EventType == 1 {
-			break
-		}
-		wReadConsoleInput.Call(stdin, uintptr(unsafe.Pointer(&rec)), 1, uintptr(unsafe.Pointer(&reads))) // Discard non Keyboard events
-	}
-	if reads == 0 {
-		return e
-	}
-	return GetKey()
+	key, _ := InkeyErr()
+	return key
+}
+
+// InkeyErr returns 0 key if no input available or a keypress if available.  Does not block or wait.
+func InkeyErr() (KeyEvent, error) {
+	key, err := readKey(false)
+	return key, opError("Inkey", err)
 }
 
 // GetBeep returns true if a beep will make a sound or false if it will be silent.
@@ -546,7 +475,7 @@ func SetBeep(value bool) {
 // Beep plays a sound if SetBeep was passed true
 func Beep() {
 	if doBeep {
-		fmt.Print('\a')
+		Print("\a")
 	}
 }
 
@@ -0,0 +1,84 @@
+//go:build !windows
+
+package cons
+
+import "errors"
+
+// consoleState is empty where there is no console to restore
+type consoleState struct{}
+
+func sysIsTerminal() bool {
+	return false
+}
+
+func sysSaveState() consoleState {
+	return consoleState{}
+}
+
+func sysRestore(state consoleState) error {
+	return nil
+}
+
+func sysSetTitle(title string) error {
+	return ErrNoConsole
+}
+
+func sysGetTitle() (string, error) {
+	return "", ErrNoConsole
+}
+
+func sysIsFullScreen() (bool, error) {
+	return false, ErrNoConsole
+}
+
+func sysSetDisplayMode(mode uintptr) error {
+	return errors.ErrUnsupported
+}
+
+func sysSetColor(foreground int8, background int8) error {
+	return ErrNoConsole
+}
+
+func sysGetColor() (int8, int8, error) {
+	return ColorWhite, ColorBlack, ErrNoConsole
+}
+
+func sysWindowSize() (int, int, error) {
+	return 24, 80, ErrNoConsole
+}
+
+func sysBufferSize() (int, int, error) {
+	return 24, 80, ErrNoConsole
+}
+
+func sysCursor() (int, int, error) {
+	return 0, 0, ErrNoConsole
+}
+
+func sysLocate(row int, col int) error {
+	return ErrNoConsole
+}
+
+func sysSetWindowAndBufferSize(rows int, cols int, bufRows int, bufCols int) error {
+	return ErrNoConsole
+}
+
+func sysCls() error {
+	return ErrNoConsole
+}
+
+func sysGetKey() (KeyEvent, error) {
+	return KeyEvent{}, ErrNoConsole
+}
+
+func sysInkey() (KeyEvent, error) {
+	return KeyEvent{}, ErrNoConsole
+}
+
+func sysCapture() (*Snapshot, error) {
+	return nil, ErrNoConsole
+}
+
+func sysCapabilities() (Capabilities, bool) {
+	return Capabilities{}, false
+}
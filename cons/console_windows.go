@@ -0,0 +1,393 @@
+package cons
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+)
+
+var (
+	kernel32DLL                 = syscall.NewLazyDLL("kernel32.dll")
+	wGetConsoleTitle            = kernel32DLL.NewProc("GetConsoleTitleW")
+	wSetConsoleTitle            = kernel32DLL.NewProc("SetConsoleTitleW")
+	wGetConsoleDisplayMode      = kernel32DLL.NewProc("GetConsoleDisplayMode")
+	wSetConsoleDisplayMode      = kernel32DLL.NewProc("SetConsoleDisplayMode")
+	wSetConsoleTextAttribute    = kernel32DLL.NewProc("SetConsoleTextAttribute")
+	wGetConsoleScreenBufferInfo = kernel32DLL.NewProc("GetConsoleScreenBufferInfo")
+	wSetConsoleCursorPosition   = kernel32DLL.NewProc("SetConsoleCursorPosition")
+	wSetConsoleWindowInfo       = kernel32DLL.NewProc("SetConsoleWindowInfo")
+	wSetConsoleScreenBufferSize = kernel32DLL.NewProc("SetConsoleScreenBufferSize")
+	wFillConsoleOutputCharacter = kernel32DLL.NewProc("FillConsoleOutputCharacterA")
+	wFillConsoleOutputAttribute = kernel32DLL.NewProc("FillConsoleOutputAttribute")
+	wSetConsoleCP               = kernel32DLL.NewProc("SetConsoleCP")
+	wSetConsoleOutputCP         = kernel32DLL.NewProc("SetConsoleOutputCP")
+	wSetConsoleMode             = kernel32DLL.NewProc("SetConsoleMode")
+	wReadConsoleInput           = kernel32DLL.NewProc("ReadConsoleInputA")
+	wPeekConsoleInput           = kernel32DLL.NewProc("PeekConsoleInputA")
+	wGetStdHandle               = kernel32DLL.NewProc("GetStdHandle")
+	wGetConsoleMode             = kernel32DLL.NewProc("GetConsoleMode")
+	wGetConsoleCursorInfo       = kernel32DLL.NewProc("GetConsoleCursorInfo")
+	wSetConsoleCursorInfo       = kernel32DLL.NewProc("SetConsoleCursorInfo")
+	wReadConsoleOutput          = kernel32DLL.NewProc("ReadConsoleOutputW")
+)
+
+const (
+	wVkShift   = 0x10
+	wVkControl = 0x11
+	wVkMenu    = 0x12
+	wVkCapital = 0x14
+	wVkLWin    = 0x5b
+	wVkRWin    = 0x5c
+
+	wCapsLockOn       = 0x80
+	wEnhancedKey      = 0x100
+	wLeftAltPressed   = 0x2
+	wLeftCtrlPressed  = 0x8
+	wNumlockOn        = 0x20
+	wRightAltPressed  = 0x1
+	wRightCtrlPressed = 0x4
+	wScrollLockOn     = 0x40
+	wShiftPressed     = 0x10
+)
+
+type (
+	// wCharInfo is the windows CHAR_INFO structure
+	wCharInfo struct {
+		UnicodeChar uint16
+		Attributes  uint16
+	}
+
+	// wConsoleCursorInfo is the windows CONSOLE_CURSOR_INFO
+	wConsoleCursorInfo struct {
+		Size    uint32
+		Visible int32
+	}
+
+	// wConsoleScreenBufferInfo is the windows CONSOLE_SCREEN_BUFFER_INFO
+	wConsoleScreenBufferInfo struct {
+		Size              wCoord
+		CursorPosition    wCoord
+		Attributes        uint16
+		Window            wSmallRect
+		MaximumWindowSize wCoord
+	}
+
+	// wCoord is the windows COORD structure
+	wCoord struct {
+		X int16
+		Y int16
+	}
+
+	// wSmallRect is the windows SMALL_RECT structure
+	wSmallRect struct {
+		Left   int16
+		Top    int16
+		Right  int16
+		Bottom int16
+	}
+
+	// wInputRecord is the windows INPUT_RECORD structure
+	wInputRecord struct {
+		EventType uint16
+		KeyEvent  wKeyEventRecord
+	}
+
+	// wKeyEventRecord is the windows KEY_EVENT_RECORD structure
+	wKeyEventRecord struct {
+		KeyDown         int32
+		RepeatCount     uint16
+		VirtualKeyCode  uint16
+		VirtualScanCode uint16
+		ASCIIChar       [2]uint8
+		ControlKeyState uint32
+	}
+
+	// wWindowBufferSize is the windows WINDOW_BUFFER_SIZEW structure
+	wWindowBufferSize struct {
+		Size wCoord
+	}
+)
+
+// consoleState is the console configuration that Restore puts back
+type consoleState struct {
+	inputMode  uint32
+	outputMode uint32
+	attributes uint16
+	cursor     wConsoleCursorInfo
+}
+
+// wCall calls a console API function, returning its error if it reports failure
+func wCall(proc *syscall.LazyProc, args ...uintptr) error {
+	if r1, _, err := proc.Call(args...); r1 == 0 {
+		return err
+	}
+	return nil
+}
+
+func coordToUintptr(coord wCoord) uintptr {
+	return uintptr(*((*uint32)(unsafe.Pointer(&coord))))
+}
+
+// sysIsTerminal returns true if stdin and stdout are both a console
+func sysIsTerminal() bool {
+	var mode uint32
+	return wCall(wGetConsoleMode, GetStdIn(), uintptr(unsafe.Pointer(&mode))) == nil &&
+		wCall(wGetConsoleMode, GetStdOut(), uintptr(unsafe.Pointer(&mode))) == nil
+}
+
+// sysSaveState reads the current console modes, colors and cursor
+func sysSaveState() consoleState {
+	var state consoleState
+	var info wConsoleScreenBufferInfo
+	stdin := GetStdIn()
+	stdout := GetStdOut()
+	wGetConsoleMode.Call(stdin, uintptr(unsafe.Pointer(&state.inputMode)))
+	wGetConsoleMode.Call(stdout, uintptr(unsafe.Pointer(&state.outputMode)))
+	wGetConsoleScreenBufferInfo.Call(stdout, uintptr(unsafe.Pointer(&info)))
+	state.attributes = info.Attributes
+	wGetConsoleCursorInfo.Call(stdout, uintptr(unsafe.Pointer(&state.cursor)))
+	return state
+}
+
+// sysRestore puts back a saved console state
+func sysRestore(state consoleState) error {
+	stdin := GetStdIn()
+	stdout := GetStdOut()
+	err := errors.Join(
+		wCall(wSetConsoleMode, stdin, uintptr(state.inputMode)),
+		wCall(wSetConsoleMode, stdout, uintptr(state.outputMode)),
+		wCall(wSetConsoleTextAttribute, stdout, uintptr(state.attributes)))
+	if state.cursor.Size != 0 {
+		err = errors.Join(err, wCall(wSetConsoleCursorInfo, stdout, uintptr(unsafe.Pointer(&state.cursor))))
+	}
+	return err
+}
+
+func sysSetTitle(title string) error {
+	return wCall(wSetConsoleTitle, uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(title))))
+}
+
+func sysGetTitle() (string, error) {
+	arr := make([]uint16, 257)
+	var arrSize = uint32(len(arr) - 1)
+
+	if err := wCall(wGetConsoleTitle, uintptr(unsafe.Pointer(&arr[0])), uintptr(arrSize)); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(utf16.Decode(arr)), " \000"), nil
+}
+
+func sysIsFullScreen() (bool, error) {
+	var mode uint32
+	if err := wCall(wGetConsoleDisplayMode, uintptr(unsafe.Pointer(&mode))); err != nil {
+		return false, err
+	}
+	return (mode == 1), nil // 1=full screen, 2=windowed
+}
+
+// sysSetDisplayMode changes between full screen (1) and windowed (2) for 32-bit applications
+func sysSetDisplayMode(mode uintptr) error {
+	var c wCoord
+	if runtime.GOARCH == "amd64" {
+		return errors.ErrUnsupported
+	}
+	return wCall(wSetConsoleDisplayMode, GetStdOut(), mode, uintptr(unsafe.Pointer(&c)))
+}
+
+func sysSetColor(foreground int8, background int8) error {
+	return wCall(wSetConsoleTextAttribute, GetStdOut(), uintptr(foreground|(background<<4)))
+}
+
+// sysScreenInfo returns the console screen buffer information
+func sysScreenInfo() (wConsoleScreenBufferInfo, error) {
+	var info wConsoleScreenBufferInfo
+	err := wCall(wGetConsoleScreenBufferInfo, GetStdOut(), uintptr(unsafe.Pointer(&info)))
+	return info, err
+}
+
+func sysGetColor() (int8, int8, error) {
+	info, err := sysScreenInfo()
+	return int8(info.Attributes & 0x0f), int8((info.Attributes >> 4) & 0x0f), err
+}
+
+func sysWindowSize() (int, int, error) {
+	info, err := sysScreenInfo()
+	return int(info.Window.Bottom - info.Window.Top + 1), int(info.Window.Right - info.Window.Left + 1), err
+}
+
+func sysBufferSize() (int, int, error) {
+	info, err := sysScreenInfo()
+	return int(info.Size.Y), int(info.Size.X), err
+}
+
+func sysCursor() (int, int, error) {
+	info, err := sysScreenInfo()
+	return int(info.CursorPosition.Y), int(info.CursorPosition.X), err
+}
+
+func sysLocate(row int, col int) error {
+	var coord wCoord
+	coord.X = int16(col)
+	coord.Y = int16(row)
+	return wCall(wSetConsoleCursorPosition, GetStdOut(), coordToUintptr(coord))
+}
+
+func sysSetWindowAndBufferSize(rows int, cols int, bufRows int, bufCols int) error {
+	var rect wSmallRect
+	rect.Top = 0
+	rect.Left = 0
+	rect.Bottom = int16(rows - 1)
+	rect.Right = int16(cols - 1)
+	stdout := GetStdOut()
+	err := wCall(wSetConsoleWindowInfo, stdout, 1, uintptr(unsafe.Pointer(&rect)))
+
+	var coord wCoord
+	coord.X = int16(bufCols)
+	coord.Y = int16(bufRows)
+	return errors.Join(err, wCall(wSetConsoleScreenBufferSize, stdout, coordToUintptr(coord)))
+}
+
+func sysCls() error {
+	var coordScreen wCoord
+	var cCharsWritten uint32
+	var dwConSize uint32
+
+	// Get the number of character cells in the current buffer.
+	stdout := GetStdOut()
+	csbi, err := sysScreenInfo()
+	if err != nil {
+		return err
+	}
+
+	dwConSize = uint32(csbi.Size.X) * uint32(csbi.Size.Y)
+
+	var c = uintptr(' ')
+	// Fill the entire screen with blanks.
+	if err := wCall(wFillConsoleOutputCharacter, stdout, c, uintptr(dwConSize), coordToUintptr(coordScreen), uintptr(unsafe.Pointer(&cCharsWritten))); err != nil {
+		return err
+	}
+
+	// Set the buffer's attributes accordingly.
+	if err := wCall(wFillConsoleOutputAttribute, stdout,
+		uintptr(csbi.Attributes),
+		uintptr(dwConSize),
+		coordToUintptr(coordScreen),
+		uintptr(unsafe.Pointer(&cCharsWritten))); err != nil {
+		return err
+	}
+
+	// Put the cursor at its home coordinates.
+	if err := wCall(wSetConsoleCursorPosition, stdout, coordToUintptr(coordScreen)); err != nil {
+		return err
+	}
+	wSetConsoleCP.Call(uintptr(65001))
+	wSetConsoleOutputCP.Call(uintptr(65001))
+	return nil
+}
+
+func sysGetKey() (KeyEvent, error) {
+	var c uint8
+	var read uint32
+	var kc uint16
+	var rec wInputRecord
+	stdin := GetStdIn()
+	if err := wCall(wSetConsoleMode, stdin, uintptr(0)); err != nil {
+		return KeyEvent{}, err
+	}
+
+	for rec.EventType != 1 || (rec.EventType == 1 && rec.KeyEvent.KeyDown == 0) || c == 0 {
+		if err := wCall(wReadConsoleInput, stdin, uintptr(unsafe.Pointer(&rec)), uintptr(1), uintptr(unsafe.Pointer(&read))); err != nil {
+			return KeyEvent{}, err
+		}
+		c = rec.KeyEvent.ASCIIChar[0]
+		switch rec.KeyEvent.VirtualKeyCode {
+		case wVkShift, wVkControl, wVkMenu, wVkCapital, wVkLWin, wVkRWin:
+			c = 0
+		}
+		kc = rec.KeyEvent.VirtualKeyCode
+		if kc >= 0x21 && kc <= 0x2f {
+			c = 1
+		}
+		if kc >= 0x70 && kc <= 0x87 {
+			c = 1
+		}
+	}
+	// Special Arrow handling
+	if kc >= 0x21 && kc <= 0x2f {
+		c = uint8(129 - 0x21 + kc)
+	}
+	if kc >= 0x70 && kc <= 0x87 {
+		c = uint8(143 - 0x70 + kc)
+	}
+
+	mods := 0
+	if (rec.KeyEvent.ControlKeyState & wCapsLockOn) != 0 {
+		mods |= KeyCapsLock
+	}
+	if (rec.KeyEvent.ControlKeyState & (wLeftAltPressed | wRightAltPressed)) != 0 {
+		mods |= KeyAlt
+	}
+	if (rec.KeyEvent.ControlKeyState & (wLeftCtrlPressed | wRightCtrlPressed)) != 0 {
+		mods |= KeyControl
+	}
+	if (rec.KeyEvent.ControlKeyState & wShiftPressed) != 0 {
+		mods |= KeyShift
+	}
+	return KeyEvent{Key: uint8(c), Modifier: int8(mods)}, nil
+}
+
+func sysInkey() (KeyEvent, error) {
+	var rec wInputRecord
+	var reads uint32
+	stdin := GetStdIn()
+	for {
+		if err := wCall(wPeekConsoleInput, stdin, uintptr(unsafe.Pointer(&rec)), 1, uintptr(unsafe.Pointer(&reads))); err != nil {
+			return KeyEvent{}, err
+		}
+		if reads == 0 {
+			return KeyEvent{}, nil
+		}
+		if rec.EventType == 1 && rec.KeyEvent.KeyDown != 0 {
+			return sysGetKey()
+		}
+		// Discard non Keyboard events
+		if err := wCall(wReadConsoleInput, stdin, uintptr(unsafe.Pointer(&rec)), 1, uintptr(unsafe.Pointer(&reads))); err != nil {
+			return KeyEvent{}, err
+		}
+	}
+}
+
+// sysCapture reads the characters and colors in the console window a row at a time
+func sysCapture() (*Snapshot, error) {
+	info, err := sysScreenInfo()
+	if err != nil {
+		return nil, err
+	}
+	rows := int(info.Window.Bottom - info.Window.Top + 1)
+	cols := int(info.Window.Right - info.Window.Left + 1)
+	snap := &Snapshot{Rows: rows, Cols: cols, Cells: make([][]Cell, rows),
+		CursorRow: int(info.CursorPosition.Y - info.Window.Top), CursorCol: int(info.CursorPosition.X - info.Window.Left)}
+	buf := make([]wCharInfo, cols)
+	size := wCoord{X: int16(cols), Y: 1}
+	for row := 0; row < rows; row++ {
+		region := wSmallRect{Left: info.Window.Left, Top: info.Window.Top + int16(row), Right: info.Window.Right, Bottom: info.Window.Top + int16(row)}
+		if err := wCall(wReadConsoleOutput, GetStdOut(), uintptr(unsafe.Pointer(&buf[0])), coordToUintptr(size), 0, uintptr(unsafe.Pointer(&region))); err != nil {
+			return nil, err
+		}
+		cells := make([]Cell, cols)
+		for col, ci := range buf {
+			cells[col] = Cell{Char: rune(ci.UnicodeChar), Foreground: int8(ci.Attributes & 0x0f), Background: int8((ci.Attributes >> 4) & 0x0f)}
+		}
+		snap.Cells[row] = cells
+	}
+	return snap, nil
+}
+
+// sysCapabilities returns the fixed capabilities of the Windows console, which uses UTF-8 and 16 colors.
+// Mouse events are not read from the console, so Mouse is false.
+func sysCapabilities() (Capabilities, bool) {
+	return Capabilities{Colors: 16, UTF8: true, LineDrawing: true, Title: true}, true
+}
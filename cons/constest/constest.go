@@ -0,0 +1,214 @@
+// Package constest runs cons menus and forms on a virtual screen with scripted keys and compares
+// snapshots of the screen with golden files, so layout changes are caught by go test on any platform.
+//
+//	h := constest.New(t, 25, 80)
+//	h.Type("7").Snapshot("typed").Keys(constest.Enter).Snapshot("chosen")
+//	cons.Choose("Main Menu", "", items, cons.LineStyleSingle, ...)
+//	h.Check()
+//
+// Golden files are kept in testdata and are written instead of compared when go test is run with -update.
+package constest
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"lib/cons"
+	"lib/dt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var update = flag.Bool("update", false, "write golden files instead of comparing with them")
+
+// Enter is the Enter key
+var Enter = cons.Key(cons.KeyEnter, 0)
+
+// ErrOutOfKeys is reported when the script has no more keys
+var ErrOutOfKeys = errors.New("constest: script ran out of keys")
+
+// TodayMask replaces a line showing today's date in golden files so they do not change from day to day
+const TodayMask = "{today}"
+
+// step is a scripted key or a point to snapshot the screen
+type step struct {
+	key      cons.KeyEvent
+	snapshot string
+}
+
+// shot is a named snapshot in golden file form
+type shot struct {
+	name string
+	text string
+}
+
+// Harness draws on a virtual screen and supplies scripted keys to GetKey and Inkey until the test ends
+type Harness struct {
+	// Screen is the virtual screen drawn on
+	Screen *cons.VirtualScreen
+	// Dir is the golden file directory, testdata by default
+	Dir   string
+	t     testing.TB
+	steps []step
+	shots []shot
+	masks []string
+}
+
+// New creates a harness with a rows by cols virtual screen and makes it the screen and key source.
+// The console, keyboard and answers are restored when the test ends.
+func New(t testing.TB, rows int, cols int) *Harness {
+	t.Helper()
+	h := &Harness{Screen: cons.NewVirtualScreen(rows, cols), Dir: "testdata", t: t}
+	previous := cons.GetAnswers()
+	cons.SetAnswers(nil)
+	cons.SetScreen(h.Screen)
+	cons.SetKeySource(h)
+	t.Cleanup(func() {
+		cons.SetKeySource(nil)
+		cons.SetScreen(nil)
+		cons.SetAnswers(previous)
+	})
+	return h
+}
+
+// Mask replaces text that changes from run to run, such as a temporary directory, with replacement in golden
+// files.  A shorter replacement is padded with spaces so the columns after it line up.
+func (h *Harness) Mask(text string, replacement string) *Harness {
+	if pad := utf8.RuneCountInString(text) - utf8.RuneCountInString(replacement); pad > 0 {
+		replacement += strings.Repeat(" ", pad)
+	}
+	h.masks = append(h.masks, text, replacement)
+	return h
+}
+
+// Keys adds key events to the script
+func (h *Harness) Keys(keys ...cons.KeyEvent) *Harness {
+	for _, key := range keys {
+		h.steps = append(h.steps, step{key: key})
+	}
+	return h
+}
+
+// Type adds the keys that type text to the script.  \n is typed as Enter.
+func (h *Harness) Type(text string) *Harness {
+	for _, r := range text {
+		if r == '\n' {
+			r = cons.KeyEnter
+		}
+		h.steps = append(h.steps, step{key: cons.RuneKey(r)})
+	}
+	return h
+}
+
+// Snapshot captures the screen as name once the keys before it have been read and the program
+// waits for the next key.  A snapshot after the last key is captured by Check.
+func (h *Harness) Snapshot(name string) *Harness {
+	h.steps = append(h.steps, step{snapshot: name})
+	return h
+}
+
+// GetKey returns the next scripted key, taking any snapshots due first.  When the script runs out of
+// keys ErrOutOfKeys is reported and Control+C is returned to end the menu or form.
+func (h *Harness) GetKey() (cons.KeyEvent, error) {
+	h.takeSnapshots()
+	if len(h.steps) == 0 {
+		h.t.Error(ErrOutOfKeys)
+		return cons.Ctrl('C'), nil
+	}
+	key := h.steps[0].key
+	h.steps = h.steps[1:]
+	return key, nil
+}
+
+// Inkey returns the next scripted key like GetKey, or a 0 key when the script is done
+func (h *Harness) Inkey() (cons.KeyEvent, error) {
+	h.takeSnapshots()
+	if len(h.steps) == 0 {
+		return cons.KeyEvent{}, nil
+	}
+	return h.GetKey()
+}
+
+// takeSnapshots captures the snapshots at the front of the script
+func (h *Harness) takeSnapshots() {
+	for len(h.steps) > 0 && h.steps[0].snapshot != "" {
+		snap, err := cons.CaptureScreen()
+		if err != nil {
+			h.t.Error(err)
+			return
+		}
+		text := strings.NewReplacer(h.masks...).Replace(Golden(snap))
+		h.shots = append(h.shots, shot{name: h.steps[0].snapshot, text: text})
+		h.steps = h.steps[1:]
+	}
+}
+
+// Check takes the remaining snapshots and compares each with its golden file, or writes the
+// golden files when go test is run with -update.  Unused keys are reported as an error.
+func (h *Harness) Check() {
+	h.t.Helper()
+	h.takeSnapshots()
+	if len(h.steps) > 0 {
+		h.t.Errorf("%d scripted keys were not read", len(h.steps))
+	}
+	for _, s := range h.shots {
+		path := filepath.Join(h.Dir, s.name+".golden")
+		if *update {
+			if err := os.MkdirAll(h.Dir, 0o755); err != nil {
+				h.t.Fatal(err)
+			}
+			if err := os.WriteFile(path, []byte(s.text), 0o644); err != nil {
+				h.t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			h.t.Errorf("%v (run go test -update to create it)", err)
+			continue
+		}
+		if diff := Diff(string(want), s.text); diff != "" {
+			h.t.Errorf("snapshot %s differs from %s (run go test -update to accept it):\n%s", s.name, path, diff)
+		}
+	}
+	h.shots = nil
+}
+
+// Golden returns a snapshot as golden file text: the screen text with the line showing
+// today's date replaced by TodayMask, followed by the cursor position
+func Golden(snap *cons.Snapshot) string {
+	today := dt.Dtols(dt.Today())
+	lines := strings.Split(strings.TrimSuffix(snap.Text(), "\n"), "\n")
+	for i, line := range lines {
+		if strings.Contains(line, today) {
+			lines[i] = TodayMask
+		}
+	}
+	return fmt.Sprintf("%s\n-- cursor %d,%d\n", strings.Join(lines, "\n"), snap.CursorRow, snap.CursorCol)
+}
+
+// Diff returns the lines that differ between want and got, or "" if they are the same
+func Diff(want string, got string) string {
+	if want == got {
+		return ""
+	}
+	w := strings.Split(want, "\n")
+	g := strings.Split(got, "\n")
+	var sb strings.Builder
+	for i := 0; i < len(w) || i < len(g); i++ {
+		var a, b string
+		if i < len(w) {
+			a = w[i]
+		}
+		if i < len(g) {
+			b = g[i]
+		}
+		if a != b {
+			fmt.Fprintf(&sb, "line %d:\n- %q\n+ %q\n", i+1, a, b)
+		}
+	}
+	return sb.String()
+}
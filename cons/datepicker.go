@@ -0,0 +1,165 @@
+package cons
+
+import (
+	"fmt"
+	"lib/dt"
+	"strings"
+	"time"
+)
+
+// DefaultDateLayout is the date layout used by DateInputField when none is given
+const DefaultDateLayout = "2006-01-02"
+
+// DateInputField makes a field a date field.  F4 or Alt+Down opens a calendar below the field and the chosen
+// date is written back using layout, a time.Format layout such as "01/02/2006".  An empty layout uses
+// DefaultDateLayout.
+func DateInputField(field *InputField, layout string) {
+	if layout == "" {
+		layout = DefaultDateLayout
+	}
+	field.popup = func(field *InputField) (string, bool, error) {
+		date, ok, err := PickDate(field.row+1, field.col, parseDate(field.value, layout))
+		if !ok || err != nil {
+			return "", false, err
+		}
+		return date.Format(layout), true, nil
+	}
+}
+
+// parseDate reads a field value in layout or a format dt.Stod accepts, or returns today if it is not a date
+func parseDate(value string, layout string) time.Time {
+	value = strings.TrimSpace(value)
+	if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+		return date
+	}
+	if strings.Count(value, "-") == 2 || strings.Count(value, "/") == 2 {
+		if date, err := dt.Stod(value); err == nil && date.Year() > 0 {
+			return date
+		}
+	}
+	return dt.Today()
+}
+
+// addMonths moves a date by months, keeping the day within the month, i.e. January 31 plus a month is February 28
+func addMonths(date time.Time, months int) time.Time {
+	first := time.Date(date.Year(), date.Month()+time.Month(months), 1, 0, 0, 0, 0, date.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(date.Day(), last)-1)
+}
+
+// calendar is the date picker popup
+type calendar struct {
+	window *Window
+	date   time.Time
+	today  time.Time
+}
+
+// Calendar colors
+const (
+	calendarForeground int8 = ColorBlack
+	calendarBackground int8 = ColorWhite
+	calendarWeekend    int8 = ColorRed
+	calendarToday      int8 = ColorCyan
+	calendarSelected   int8 = ColorBlue
+)
+
+// PickDate shows a month calendar at row, col, or as close as fits, with date selected.  Arrows move a day or
+// a week, PgUp and PgDn a month, Control+PgUp and Control+PgDn a year, Home and End to the start and end of the
+// month, and T to today.  Enter chooses the selected date and Esc cancels.  ErrInterrupted is returned for Control+C.
+func PickDate(row int, col int, date time.Time) (time.Time, bool, error) {
+	defer HoldScreen()()
+	const rows, cols = 9, 24
+	screenRows, screenCols, _ := WindowSizeErr()
+	if row+rows > screenRows {
+		row = max(0, row-rows-1)
+	}
+	col = max(0, min(col, screenCols-cols))
+	c := &calendar{window: NewWindow(row, col, rows, cols, LineStyleSingle, calendarForeground, calendarBackground),
+		date: date, today: dt.Today()}
+	c.window.Open()
+	defer c.window.Close()
+	for {
+		c.draw()
+		key, err := GetKeyErr()
+		if err != nil {
+			return date, false, err
+		}
+		years := 0
+		if key.Modifier&KeyControl != 0 {
+			years = 12
+		}
+		switch {
+		case key.Key == KeyEnter:
+			return c.date, true, nil
+		case key.Key == KeyEscape:
+			return date, false, nil
+		case key == Ctrl('C'):
+			if interrupted() {
+				return date, false, ErrInterrupted
+			}
+		case key.Key == KeyLeft:
+			c.date = c.date.AddDate(0, 0, -1)
+		case key.Key == KeyRight:
+			c.date = c.date.AddDate(0, 0, 1)
+		case key.Key == KeyUp:
+			c.date = c.date.AddDate(0, 0, -7)
+		case key.Key == KeyDown:
+			c.date = c.date.AddDate(0, 0, 7)
+		case key.Key == KeyPageUp:
+			c.date = addMonths(c.date, -max(1, years))
+		case key.Key == KeyPageDown:
+			c.date = addMonths(c.date, max(1, years))
+		case key.Key == KeyHome:
+			c.date = c.date.AddDate(0, 0, 1-c.date.Day())
+		case key.Key == KeyEnd:
+			c.date = addMonths(c.date.AddDate(0, 0, 1-c.date.Day()), 1).AddDate(0, 0, -1)
+		case key.Key == 't' || key.Key == 'T':
+			c.date = c.today
+		default:
+			Beep()
+		}
+	}
+}
+
+// draw draws the month of the selected date and leaves the cursor on it
+func (c *calendar) draw() {
+	w := c.window
+	w.Title = fmt.Sprint(c.date.Month(), " ", c.date.Year())
+	w.Draw()
+	top, left, _, _ := w.Inner()
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		c.setColor(day, false, false)
+		Locate(top, left+int(day)*3)
+		Print(" ", day.String()[:2])
+	}
+	first := c.date.AddDate(0, 0, 1-c.date.Day())
+	var cursorRow, cursorCol int
+	for d := first; d.Month() == first.Month(); d = d.AddDate(0, 0, 1) {
+		week := (d.Day() - 1 + int(first.Weekday())) / 7
+		row, col := top+1+week, left+int(d.Weekday())*3
+		selected := d.Day() == c.date.Day()
+		c.setColor(d.Weekday(), d.Equal(c.today), selected)
+		Locate(row, col+1)
+		Printf("%2d", d.Day())
+		if selected {
+			cursorRow, cursorCol = row, col+2
+		}
+	}
+	SetColor(calendarForeground, calendarBackground)
+	Locate(cursorRow, cursorCol)
+}
+
+// setColor sets the colors for a day of the week, today or the selected day
+func (c *calendar) setColor(day time.Weekday, today bool, selected bool) {
+	foreground, background := calendarForeground, calendarBackground
+	if day == time.Saturday || day == time.Sunday {
+		foreground = calendarWeekend
+	}
+	if today {
+		background = calendarToday
+	}
+	if selected {
+		foreground, background = ColorBrightWhite, calendarSelected
+	}
+	SetColor(foreground, background)
+}
@@ -0,0 +1,270 @@
+package cons
+
+import (
+	"lib/str"
+	"strings"
+)
+
+// maxUndo is the number of edits remembered per field
+const maxUndo = 100
+
+// fieldEdit is a saved field value for undo and redo
+type fieldEdit struct {
+	value  string
+	offset int
+}
+
+var clipboard string
+
+// GetClipboard returns the text last cut or copied from a field
+func GetClipboard() string {
+	return clipboard
+}
+
+// SetClipboard sets the text that will be pasted into a field
+func SetClipboard(text string) {
+	clipboard = text
+}
+
+// pushUndo saves the field value before an edit and forgets anything that could be redone
+func pushUndo(field *InputField, offset int) {
+	if field.secret {
+		return
+	}
+	field.undo = append(field.undo, fieldEdit{value: field.value, offset: offset})
+	if len(field.undo) > maxUndo {
+		field.undo = field.undo[1:]
+	}
+	field.redo = nil
+}
+
+// undoField moves the last edit from one stack to the other, restoring its value.  Returns false if there was nothing to restore.
+func undoField(field *InputField, from *[]fieldEdit, to *[]fieldEdit, offset *int) bool {
+	if len(*from) == 0 {
+		return false
+	}
+	edit := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, fieldEdit{value: field.value, offset: *offset})
+	field.value = edit.value
+	field.selecting = false
+	*offset = min(edit.offset, len([]rune(edit.value)))
+	drawField(field, *offset)
+	return true
+}
+
+// shownValue returns the field value as it is displayed, masking a secret field
+func shownValue(field *InputField) string {
+	if !field.secret {
+		return field.value
+	}
+	if field.mask == 0 {
+		return ""
+	}
+	return strings.Repeat(string(field.mask), len([]rune(field.value)))
+}
+
+// isMovement returns true for actions that move the cursor within a field and can extend a selection
+func isMovement(action Action) bool {
+	switch action {
+	case ActionLeft, ActionRight, ActionWordLeft, ActionWordRight, ActionHome, ActionEnd:
+		return true
+	}
+	return false
+}
+
+// selection returns the selected range of the field.  ok is false if nothing is selected.
+func selection(field *InputField, offset int) (start int, end int, ok bool) {
+	if !field.selecting || field.anchor == offset {
+		return offset, offset, false
+	}
+	length := len([]rune(field.value))
+	start = min(min(field.anchor, offset), length)
+	end = min(max(field.anchor, offset), length)
+	return start, end, start < end
+}
+
+// clearSelection removes the selection highlight from the field
+func clearSelection(field *InputField) {
+	if field.selecting {
+		field.selecting = false
+		drawField(field, 0)
+	}
+}
+
+// drawField redraws the field value with the selection shown in reversed colors.  A field placed narrower
+// than its size shows the columns from its scroll position.
+func drawField(field *InputField, offset int) {
+	width := field.size - field.hidden
+	value := []rune(str.LeftPad(shownValue(field), field.scroll+width, " "))[field.scroll:]
+	start, end, _ := selection(field, offset)
+	start = max(0, min(start-field.scroll, width))
+	end = max(0, min(end-field.scroll, width))
+	Locate(field.row, field.col)
+	Print(string(value[:start]))
+	if start < end {
+		foreground, background := GetColor()
+		SetColor(background, foreground)
+		Print(string(value[start:end]))
+		SetColor(foreground, background)
+	}
+	Print(string(value[end:]))
+}
+
+// fieldEditor is the cursor and typing state of the field being edited
+type fieldEditor struct {
+	field  *InputField
+	offset int
+	typing bool
+}
+
+// locate puts the cursor in the field.  A hidden secret keeps the cursor at the start.
+func (e *fieldEditor) locate() {
+	if e.field.secret && e.field.mask == 0 {
+		Locate(e.field.row, e.field.col)
+	} else {
+		Locate(e.field.row, e.field.col+e.offset-e.field.scroll)
+	}
+}
+
+// key applies an editing action or a typed character to the field.  It returns false for actions that do not
+// edit a field, such as moving to the next field, so the caller can handle them.
+func (e *fieldEditor) key(action Action, ch KeyEvent) (bool, error) {
+	field := e.field
+	value := []rune(field.value)
+	if field.secret {
+		defer clear(value)
+	}
+	if isMovement(action) {
+		if ch.Modifier&KeyShift != 0 {
+			if !field.selecting {
+				field.selecting = true
+				field.anchor = e.offset
+			}
+		} else {
+			clearSelection(field)
+		}
+	}
+	if action != ActionNone {
+		e.typing = false
+	}
+	switch action {
+	case ActionLeft:
+		if e.offset > 0 {
+			e.offset--
+		}
+	case ActionRight:
+		if e.offset < len(value) {
+			e.offset++
+		}
+	case ActionWordLeft:
+		e.offset = wordLeft(value, e.offset)
+	case ActionWordRight:
+		e.offset = wordRight(value, e.offset)
+	case ActionHome:
+		e.offset = 0
+	case ActionEnd:
+		e.offset = len([]rune(strings.TrimRight(field.value, " \t\r\n")))
+	case ActionBackspace:
+		if start, end, ok := selection(field, e.offset); ok {
+			e.offset = editField(field, value, start, end, nil)
+		} else if e.offset > 0 {
+			e.offset = editField(field, value, e.offset-1, e.offset, nil)
+		} else {
+			Beep()
+		}
+	case ActionInsertSpace:
+		if e.offset < len(value) && len(value) < field.size {
+			editField(field, value, e.offset, e.offset, []rune{' '})
+		} else {
+			Beep()
+		}
+	case ActionDeleteChar:
+		if start, end, ok := selection(field, e.offset); ok {
+			e.offset = editField(field, value, start, end, nil)
+		} else if e.offset < len(value) {
+			editField(field, value, e.offset, e.offset+1, nil)
+		}
+	case ActionDeleteToEnd:
+		editField(field, value, e.offset, len(value), nil)
+	case ActionClearField:
+		e.offset = editField(field, value, 0, len(value), nil)
+	case ActionUndo:
+		if !undoField(field, &field.undo, &field.redo, &e.offset) {
+			Beep()
+		}
+	case ActionRedo:
+		if !undoField(field, &field.redo, &field.undo, &e.offset) {
+			Beep()
+		}
+	case ActionRevertField:
+		if field.value != field.original {
+			e.offset = editField(field, value, 0, len(value), []rune(field.original))
+		}
+	case ActionCopy, ActionCut:
+		if field.secret {
+			Beep()
+			break
+		}
+		start, end, ok := selection(field, e.offset)
+		if !ok {
+			start, end = 0, len(value)
+		}
+		clipboard = string(value[start:end])
+		if action == ActionCut {
+			e.offset = editField(field, value, start, end, nil)
+		}
+	case ActionPaste:
+		start, end, ok := selection(field, e.offset)
+		if !ok {
+			start, end = e.offset, e.offset
+		}
+		paste := []rune(strings.NewReplacer("\r", "", "\n", " ", "\t", " ").Replace(clipboard))
+		paste = paste[:max(0, min(len(paste), field.size-(len(value)-(end-start))))]
+		if len(paste) == 0 || !isTextValid(field, paste) {
+			Beep()
+			break
+		}
+		e.offset = editField(field, value, start, end, paste) + len(paste)
+	case ActionPopup:
+		if field.popup == nil {
+			Beep()
+			break
+		}
+		chosen, ok, err := field.popup(field)
+		if err != nil {
+			return true, err
+		}
+		if ok && chosen != field.value {
+			text := []rune(chosen)
+			if len(text) > field.size || !isTextValid(field, text) {
+				Beep()
+				break
+			}
+			e.offset = editField(field, value, 0, len(value), text) + len(text)
+		}
+	case ActionNone:
+		start, end, ok := selection(field, e.offset)
+		if !ok {
+			start, end = e.offset, min(e.offset+1, len(value))
+		}
+		if start < field.size && isPrintable(ch) && isKeyValid(field, ch) {
+			if !e.typing {
+				pushUndo(field, e.offset)
+			}
+			e.typing = true
+			field.value = string(append(value[:start:start], append([]rune{keyRune(ch)}, value[end:]...)...))
+			field.selecting = false
+			e.offset = start + 1
+			drawField(field, e.offset)
+		} else {
+			Beep()
+		}
+	default:
+		return false, nil
+	}
+	if field.selecting {
+		drawField(field, e.offset)
+	}
+	return true, nil
+}
@@ -0,0 +1,329 @@
+package cons
+
+import (
+	"fmt"
+	"lib/dt"
+	"lib/fixed"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// typeAheadPause is how long after the last typed character type-ahead starts over
+const typeAheadPause = time.Second
+
+// FilePicker is a dialog for choosing a file or directory.  It lists the directories and the files matching
+// Pattern with their size and modification date.  Up, Down, PgUp, PgDn, Home and End move, typing selects the
+// first name starting with what was typed, Enter opens a directory or chooses a file, Backspace goes to the
+// parent directory, F2 types a new file name, F3 changes the filter and Esc cancels.
+type FilePicker struct {
+	// Title is drawn in the top border
+	Title string
+	// Dir is the directory shown first, or the working directory if empty
+	Dir string
+	// Pattern is a filepath.Match pattern the file names must match, i.e. "*.csv".  Empty matches all files.
+	Pattern string
+	// Directories chooses a directory instead of a file
+	Directories bool
+	// NewName allows typing the name of a file that does not exist yet
+	NewName bool
+	// Foreground and Background are the window colors
+	Foreground int8
+	Background int8
+	// SelectForeground and SelectBackground are the colors of the selected entry
+	SelectForeground int8
+	SelectBackground int8
+	entries          []fileEntry
+	selected         int
+	top              int
+	typed            string
+	typedAt          time.Time
+	message          string
+	window           *Window
+}
+
+// fileEntry is a listed file or directory
+type fileEntry struct {
+	name     string
+	dir      bool
+	size     int64
+	modified time.Time
+}
+
+// NewFilePicker creates a file picker starting in dir that lists files matching pattern
+func NewFilePicker(title string, dir string, pattern string) *FilePicker {
+	return &FilePicker{Title: title, Dir: dir, Pattern: pattern, Foreground: ColorBlack, Background: ColorWhite,
+		SelectForeground: ColorBrightWhite, SelectBackground: ColorBlue}
+}
+
+// Pick shows the dialog and returns the chosen path, or false if it was cancelled.  In line mode the path is
+// read as a line of text.  ErrInterrupted is returned for Control+C.
+func (p *FilePicker) Pick() (string, bool, error) {
+	dir, err := filepath.Abs(p.Dir)
+	if err != nil {
+		return "", false, err
+	}
+	if lineMode {
+		fmt.Printf("%s [%s]: ", p.Title, dir)
+		line, err := readLine()
+		if err != nil || strings.TrimSpace(line) == "" {
+			return "", false, nil
+		}
+		line = strings.TrimSpace(line)
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(dir, line)
+		}
+		return line, true, nil
+	}
+	defer HoldScreen()()
+	if err := p.load(dir); err != nil {
+		return "", false, err
+	}
+	rows, cols, _ := WindowSizeErr()
+	p.window = CenteredWindow(min(rows, 22), min(cols, 76), LineStyleSingle, p.Foreground, p.Background)
+	p.window.Title = p.Title
+	p.window.Open()
+	defer p.window.Close()
+	for {
+		p.draw()
+		key, err := GetKeyErr()
+		if err != nil {
+			return "", false, err
+		}
+		listRows := p.listRows()
+		p.message = ""
+		if !isPrintable(key) || key.Modifier&(KeyControl|KeyAlt) != 0 || key.Key == ' ' && p.typed == "" {
+			p.typed = ""
+		}
+		switch {
+		case key.Key == KeyEscape:
+			return "", false, nil
+		case key == Ctrl('C'):
+			if interrupted() {
+				return "", false, ErrInterrupted
+			}
+		case key.Key == KeyUp:
+			p.selected--
+		case key.Key == KeyDown:
+			p.selected++
+		case key.Key == KeyPageUp:
+			p.selected -= listRows
+		case key.Key == KeyPageDown:
+			p.selected += listRows
+		case key.Key == KeyHome:
+			p.selected = 0
+		case key.Key == KeyEnd:
+			p.selected = len(p.entries) - 1
+		case key.Key == KeyBackspace:
+			p.open(filepath.Dir(p.Dir), filepath.Base(p.Dir))
+		case key.Key == KeyEnter:
+			if len(p.entries) == 0 {
+				Beep()
+				break
+			}
+			entry := p.entries[p.selected]
+			switch {
+			case entry.name == ".":
+				return p.Dir, true, nil
+			case entry.name == "..":
+				p.open(filepath.Dir(p.Dir), filepath.Base(p.Dir))
+			case entry.dir:
+				p.open(filepath.Join(p.Dir, entry.name), "")
+			default:
+				return filepath.Join(p.Dir, entry.name), true, nil
+			}
+		case key.Key == KeyF2 && p.NewName && !p.Directories:
+			name, ok, err := p.prompt("Name: ", "")
+			if err != nil {
+				return "", false, err
+			}
+			if ok && name != "" {
+				return filepath.Join(p.Dir, name), true, nil
+			}
+		case key.Key == KeyF3 && !p.Directories:
+			pattern, ok, err := p.prompt("Filter: ", p.Pattern)
+			if err != nil {
+				return "", false, err
+			}
+			if !ok {
+				break
+			}
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				p.message = "Invalid filter"
+				break
+			}
+			p.Pattern = pattern
+			p.open(p.Dir, "")
+		case isPrintable(key) && key.Modifier&(KeyControl|KeyAlt) == 0:
+			p.typeAhead(keyRune(key))
+		default:
+			Beep()
+		}
+		p.selected = max(0, min(p.selected, len(p.entries)-1))
+		p.top = max(min(p.top, p.selected), p.selected-listRows+1)
+	}
+}
+
+// typeAhead adds a character to the typed prefix and selects the first entry starting with it
+func (p *FilePicker) typeAhead(c rune) {
+	if time.Since(p.typedAt) > typeAheadPause {
+		p.typed = ""
+	}
+	p.typed += string(c)
+	p.typedAt = time.Now()
+	for i, entry := range p.entries {
+		if strings.HasPrefix(strings.ToLower(entry.name), strings.ToLower(p.typed)) {
+			p.selected = i
+			return
+		}
+	}
+	Beep()
+}
+
+// open shows dir with the entry named selected, if any, selected.  The current directory stays if dir cannot be read.
+func (p *FilePicker) open(dir string, selected string) {
+	current := p.Dir
+	if err := p.load(dir); err != nil {
+		p.message = err.Error()
+		p.load(current)
+		return
+	}
+	p.top = 0
+	for i, entry := range p.entries {
+		if entry.name == selected {
+			p.selected = i
+		}
+	}
+}
+
+// load reads dir, listing the directories and the files that match the pattern
+func (p *FilePicker) load(dir string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	p.Dir, p.selected, p.typed = dir, 0, ""
+	p.entries = p.entries[:0]
+	if p.Directories {
+		p.entries = append(p.entries, fileEntry{name: ".", dir: true})
+	}
+	if filepath.Dir(dir) != dir {
+		p.entries = append(p.entries, fileEntry{name: "..", dir: true})
+	}
+	var list []fileEntry
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		isDir := info.IsDir()
+		if !isDir && info.Mode()&os.ModeSymlink != 0 {
+			if target, err := os.Stat(filepath.Join(dir, file.Name())); err == nil {
+				isDir = target.IsDir()
+			}
+		}
+		if !isDir {
+			if p.Directories {
+				continue
+			}
+			if matched, _ := filepath.Match(p.Pattern, file.Name()); p.Pattern != "" && !matched {
+				continue
+			}
+		}
+		list = append(list, fileEntry{name: file.Name(), dir: isDir, size: info.Size(), modified: info.ModTime()})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].dir != list[j].dir {
+			return list[i].dir
+		}
+		return strings.ToLower(list[i].name) < strings.ToLower(list[j].name)
+	})
+	p.entries = append(p.entries, list...)
+	return nil
+}
+
+// listRows returns the number of entries shown at once
+func (p *FilePicker) listRows() int {
+	_, _, rows, _ := p.window.Inner()
+	return max(1, rows-2)
+}
+
+// draw draws the directory, the entries and the filter or message
+func (p *FilePicker) draw() {
+	top, left, rows, cols := p.window.Inner()
+	SetColor(p.Foreground, p.Background)
+	Locate(top, left)
+	dir := []rune(p.Dir)
+	if len(dir) > cols {
+		ellipsis := []rune("…")
+		if !canDrawUnicode() {
+			ellipsis = []rune("...")
+		}
+		dir = append(ellipsis, dir[min(len(dir), len(dir)-cols+len(ellipsis)):]...)
+	}
+	Print(fitText(string(dir), cols))
+	nameWidth := max(1, cols-25)
+	for r := 0; r < p.listRows(); r++ {
+		index := p.top + r
+		Locate(top+1+r, left)
+		line := ""
+		if index < len(p.entries) {
+			entry := p.entries[index]
+			name := entry.name
+			size := "<DIR>"
+			date := ""
+			if entry.dir {
+				name += string(filepath.Separator)
+			} else {
+				value := fixed.FromInt64(entry.size)
+				size = value.ToString(0, ',', 0)
+			}
+			if !entry.modified.IsZero() {
+				date = dt.Dtos(entry.modified)
+			}
+			line = fmt.Sprintf(" %s %12s %10s", fitText(name, nameWidth-1), size, date)
+		}
+		if index == p.selected {
+			SetColor(p.SelectForeground, p.SelectBackground)
+		}
+		Print(fitText(line, cols))
+		SetColor(p.Foreground, p.Background)
+	}
+	Locate(top+rows-1, left)
+	status := p.message
+	if status == "" {
+		var parts []string
+		if p.Pattern != "" && !p.Directories {
+			parts = append(parts, "Filter: "+p.Pattern)
+		}
+		parts = append(parts, "Enter Open")
+		if p.NewName && !p.Directories {
+			parts = append(parts, "F2 Name")
+		}
+		if !p.Directories {
+			parts = append(parts, "F3 Filter")
+		}
+		status = strings.Join(append(parts, "Esc Cancel"), "  ")
+	}
+	Print(fitText(status, cols))
+	Locate(top+1+p.selected-p.top, left)
+}
+
+// prompt reads a line of text on the last row of the window, starting with value.  ok is false if it was canceled.
+func (p *FilePicker) prompt(label string, value string) (text string, ok bool, err error) {
+	top, left, rows, cols := p.window.Inner()
+	SetColor(p.Foreground, p.Background)
+	Locate(top+rows-1, left)
+	Print(fitText(label, cols))
+	Locate(top+rows-1, left+len(label))
+	editor := LineEditor{Max: cols - len(label) - 1, Value: value}
+	if ok, err = editor.edit(); !ok {
+		return "", false, err
+	}
+	text = string(editor.buf)
+	editor.wipe()
+	return strings.TrimFunc(text, unicode.IsSpace), true, nil
+}
@@ -0,0 +1,221 @@
+package cons_test
+
+import (
+	"fmt"
+	"lib/cons"
+	"lib/cons/constest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoldenChoose(t *testing.T) {
+	h := constest.New(t, 24, 80)
+	h.Snapshot("choose_start").Type("9\n").Snapshot("choose_invalid").Type("2\n").Snapshot("choose_done")
+	c, err := cons.ChooseErr("Main Menu", "Golden", []string{"Add", "Change", "Delete"}, cons.LineStyleSingle,
+		cons.ColorWhite, cons.ColorBlue, cons.ColorBrightWhite, cons.ColorBlack, cons.ColorBrightRed)
+	if err != nil || c != 2 {
+		t.Error("Expected choice 2, got", c, err)
+	}
+	h.Check()
+}
+
+func TestGoldenChooseTwoColumns(t *testing.T) {
+	h := constest.New(t, 24, 80)
+	h.Type("10\n").Snapshot("choose_columns")
+	items := []string{"One", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine", "Ten"}
+	if c, err := cons.ChooseErr("Numbers", "", items, cons.LineStyleDouble,
+		cons.ColorWhite, cons.ColorBlue, cons.ColorBrightWhite, cons.ColorBlack, cons.ColorBrightRed); c != 10 {
+		t.Error("Expected choice 10, got", c, err)
+	}
+	h.Check()
+}
+
+func TestGoldenEntry(t *testing.T) {
+	h := constest.New(t, 24, 80)
+	fields := []cons.InputField{
+		cons.NewInputField("Name", "", 20),
+		cons.NewInputField("City", "Boston", 15),
+	}
+	h.Snapshot("entry_start").Type("Ada").Keys(cons.Key(cons.KeyTab, 0)).Snapshot("entry_typed").
+		Keys(cons.Key(cons.KeyF10, 0)).Snapshot("entry_done")
+	ok, err := cons.EntryErr("Customer", "", fields, cons.ColorWhite, cons.ColorBlue,
+		cons.ColorBrightWhite, cons.ColorBlack, cons.LineStyleSingle)
+	if !ok || err != nil || cons.FieldValue(&fields[0]) != "Ada" {
+		t.Error("Expected the form to be saved with Ada, got", ok, err, cons.FieldValue(&fields[0]))
+	}
+	h.Check()
+}
+
+func TestGoldenEntryStatusBar(t *testing.T) {
+	h := constest.New(t, 24, 80)
+	bar := cons.NewStatusBar(cons.ColorBlack, cons.ColorCyan).AddKey(cons.Key(cons.KeyF1, 0), "Help")
+	bar.SetClock("")
+	bar.SetInfo("Record 1 of 1")
+	bar.Show()
+	defer func() {
+		bar.Hide()
+		cons.RunPosted()
+	}()
+	fields := []cons.InputField{cons.NewInputField("Name", "Ada", 20)}
+	h.Snapshot("entry_status").Keys(cons.Key(cons.KeyEscape, 0))
+	if ok, _ := cons.EntryErr("Customer", "", fields, cons.ColorWhite, cons.ColorBlue,
+		cons.ColorBrightWhite, cons.ColorBlack, cons.LineStyleSingle); ok {
+		t.Error("Expected Esc to cancel the form")
+	}
+	h.Check()
+}
+
+func TestGoldenPager(t *testing.T) {
+	h := constest.New(t, 10, 40)
+	var lines []string
+	for i := 1; i <= 30; i++ {
+		lines = append(lines, fmt.Sprintf("line %d\tof the report", i))
+	}
+	lines[3] = "a wide line " + strings.Repeat("-", 40) + " END"
+	p := cons.NewPager("Report", lines)
+	p.LineNumbers = true
+	h.Snapshot("pager_start").Keys(cons.Key(cons.KeyPageDown, 0)).Snapshot("pager_page").
+		Type("/LINE 2\n").Snapshot("pager_found").Type("n").Snapshot("pager_next").
+		Keys(cons.Key(cons.KeyEnd, 0)).Snapshot("pager_end").
+		Keys(cons.Key(cons.KeyHome, 0), cons.Key(cons.KeyRight, cons.KeyControl), cons.Key(cons.KeyRight, cons.KeyControl)).
+		Snapshot("pager_right").
+		Type("/nothing\n").Snapshot("pager_missing").Type("q")
+	if err := p.Show(); err != nil {
+		t.Error(err)
+	}
+	h.Check()
+}
+
+func TestGoldenDatePicker(t *testing.T) {
+	h := constest.New(t, 16, 40)
+	fields := []cons.InputField{cons.NewInputField("Due", "02/10/2026", 10)}
+	cons.PositionInputField(&fields[0], 1, 5)
+	cons.DateInputField(&fields[0], "01/02/2006")
+	h.Keys(cons.Key(cons.KeyF4, 0)).Snapshot("date_open").
+		Keys(cons.Key(cons.KeyPageDown, 0), cons.Key(cons.KeyRight, 0)).Snapshot("date_moved").
+		Keys(cons.Key(cons.KeyEnter, 0)).Snapshot("date_chosen").Keys(cons.Key(cons.KeyF10, 0))
+	if ok, err := cons.StartEntryErr(fields, cons.DefaultKeyMap()); !ok || err != nil || cons.FieldValue(&fields[0]) != "03/11/2026" {
+		t.Error("Expected 03/11/2026, got", ok, err, cons.FieldValue(&fields[0]))
+	}
+	h.Check()
+}
+
+func TestGoldenCalculator(t *testing.T) {
+	h := constest.New(t, 20, 40)
+	fields := []cons.InputField{cons.NewInputField("Amount", "19.99", 12)}
+	cons.PositionInputField(&fields[0], 1, 8)
+	cons.CurrencyInputField(&fields[0], 2)
+	h.Keys(cons.Key(cons.KeyF4, 0)).Type("*3+").Snapshot("calc_running").Type("5%").Snapshot("calc_percent").
+		Keys(cons.Key(cons.KeyEnter, 0)).Snapshot("calc_pasted").Keys(cons.Key(cons.KeyF10, 0))
+	if ok, err := cons.StartEntryErr(fields, cons.DefaultKeyMap()); !ok || err != nil || cons.FieldValue(&fields[0]) != "62.97" {
+		t.Error("Expected 62.97, got", ok, err, cons.FieldValue(&fields[0]))
+	}
+	h.Check()
+}
+
+func TestGoldenFilePicker(t *testing.T) {
+	dir := t.TempDir()
+	modified := time.Date(2024, time.March, 5, 12, 0, 0, 0, time.Local)
+	for name, size := range map[string]int64{"alpha.csv": 1234, "beta.txt": 10, "big.csv": 1234567, "sub": -1} {
+		path := filepath.Join(dir, name)
+		if size < 0 {
+			os.Mkdir(path, 0o755)
+		} else if err := os.WriteFile(path, nil, 0o644); err != nil || os.Truncate(path, size) != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, modified, modified)
+	}
+	h := constest.New(t, 24, 80).Mask(dir, "{dir}")
+	h.Snapshot("files_start").Type("bi").Snapshot("files_typeahead").
+		Keys(cons.Key(cons.KeyF3, 0)).Type("*.csv\n").Snapshot("files_filtered").
+		Keys(cons.Key(cons.KeyF3, 0)).Type("x").Keys(cons.Key(cons.KeyEscape, 0), cons.Key(cons.KeyDown, 0), cons.Key(cons.KeyDown, 0), cons.Key(cons.KeyEnter, 0))
+	picker := cons.NewFilePicker("Import", dir, "")
+	picker.NewName = true
+	path, ok, err := picker.Pick()
+	if !ok || err != nil || path != filepath.Join(dir, "alpha.csv") {
+		t.Error("Expected alpha.csv, got", path, ok, err)
+	}
+	if picker.Pattern != "*.csv" {
+		t.Error("Expected a canceled filter to keep *.csv, got", picker.Pattern)
+	}
+	h.Check()
+}
+
+func TestGoldenTabSet(t *testing.T) {
+	h := constest.New(t, 10, 40)
+	general := []cons.InputField{cons.NewInputField("Company", "Acme", 12), cons.NewInputField("Year", "2026", 4)}
+	ledger := []cons.InputField{cons.NewInputField("Cash account", "1000", 6)}
+	tabs := cons.NewTabSet("Settings", cons.NewTab("&General", general), cons.NewTab("&Ledger", ledger))
+	h.Snapshot("tabs_general").Keys(cons.Key(cons.KeyPageDown, cons.KeyControl)).Snapshot("tabs_ledger").
+		Type("1010").Keys(cons.Alt('g')).Type("X").Snapshot("tabs_back").Keys(cons.Key(cons.KeyF10, 0))
+	if ok, err := tabs.Run(); !ok || err != nil || cons.FieldValue(&general[0]) != "Xcme" || cons.FieldValue(&ledger[0]) != "1010" {
+		t.Error("Expected Xcme and 1010, got", ok, err, cons.FieldValue(&general[0]), cons.FieldValue(&ledger[0]))
+	}
+	h.Check()
+}
+
+func TestGoldenTreeView(t *testing.T) {
+	h := constest.New(t, 10, 40)
+	assets := cons.NewTreeNode("Assets", 1000, cons.NewTreeNode("Cash", 1010), cons.NewTreeNode("Receivables", 1200))
+	expenses := cons.NewTreeNode("Expenses", 5000)
+	expenses.Load = func(node *cons.TreeNode) ([]*cons.TreeNode, error) {
+		return []*cons.TreeNode{cons.NewTreeNode("Rent", 5100), cons.NewTreeNode("Wages", 5200)}, nil
+	}
+	view := cons.NewTreeView("Accounts", assets, cons.NewTreeNode("Liabilities", 2000), expenses)
+	var selected []string
+	view.OnSelect = func(node *cons.TreeNode) { selected = append(selected, node.Label) }
+	h.Snapshot("tree_start").Keys(cons.Key(cons.KeyRight, 0)).Snapshot("tree_expanded").
+		Type("e").Keys(cons.Key(cons.KeyRight, 0), cons.Key(cons.KeyRight, 0), cons.Key(cons.KeyDown, 0)).
+		Snapshot("tree_loaded").Keys(cons.Key(cons.KeyLeft, 0)).Snapshot("tree_parent").
+		Keys(cons.Key(cons.KeyDown, 0), cons.Key(cons.KeyEnter, 0))
+	node, err := view.Show()
+	if err != nil || node == nil || node.Value != 5100 {
+		t.Error("Expected Rent, got", node, err)
+	}
+	if got := strings.Join(selected, ","); got != "Assets,Expenses,Rent,Wages,Expenses,Rent" {
+		t.Error("Unexpected selections", got)
+	}
+	h.Check()
+}
+
+func TestGoldenView(t *testing.T) {
+	h := constest.New(t, 12, 40)
+	name := cons.NewField("Name", "", 12)
+	city := cons.NewField("City", "Boston", 10)
+	name.PromptWidth, city.PromptWidth = 6, 6
+	terms := cons.NewMenu(nil, "Net 30", "Net 60", "Cash")
+	var view *cons.View
+	saved := false
+	form := cons.NewVBox(cons.NewLabel("Customer details"), name, city,
+		cons.NewHBox(terms, cons.NewVBox(cons.NewButton("OK", func() { saved = true; view.Close() }),
+			cons.NewButton("Cancel", func() { view.Close() }))))
+	form.Border, form.Title, form.Spacing = cons.LineStyleSingle, "Customer", 1
+	view = cons.NewView(form)
+	h.Snapshot("view_start").Type("Acme\t").Snapshot("view_city").Type("\tc").Snapshot("view_menu").
+		Type("\t").Snapshot("view_button").Type("\n")
+	if err := view.Run(); err != nil || !saved || name.Value() != "Acme" || terms.Selected != 2 {
+		t.Error("Expected Acme and Cash saved, got", err, saved, name.Value(), terms.Selected)
+	}
+	h.Check()
+}
+
+func TestGoldenBoxes(t *testing.T) {
+	h := constest.New(t, 12, 40)
+	cons.DrawBox(0, 0, 6, 20, cons.LineStyleRounded)
+	cons.DrawBox(0, 19, 6, 21, cons.LineStyleDoubleHorizontal)
+	cons.DrawHorizontalLine(2, 0, 40, cons.LineStyleSingle)
+	cons.DrawVerticalLine(0, 9, 6, cons.LineStyleHeavy)
+	cons.DrawBox(6, 0, 5, 12, cons.LineStyleASCII)
+	w := cons.NewWindow(7, 20, 3, 14, cons.LineStyleDouble, cons.ColorWhite, cons.ColorBlue)
+	w.Shadow, w.Title = true, "Note"
+	w.Open()
+	h.Snapshot("boxes").Check()
+	if snap, _ := cons.CaptureScreen(); snap.Cells[10][22].Background != cons.ColorBlack || snap.Cells[8][34].Foreground != cons.ColorGray {
+		t.Error("Expected a shadow below and right of the window")
+	}
+	w.Close()
+	h.Snapshot("boxes_closed").Check()
+}
@@ -0,0 +1,95 @@
+package cons
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// History is a list of lines entered into a LineEditor, optionally kept in a file
+type History struct {
+	entries []string
+	max     int
+	path    string
+}
+
+// NewHistory creates an in memory history remembering up to max lines, or all lines if max is 0
+func NewHistory(max int) *History {
+	return &History{max: max}
+}
+
+// LoadHistory reads the history file at path.  A missing file gives an empty history.
+// Lines added afterwards are appended to the file.
+func LoadHistory(path string, max int) (*History, error) {
+	h := &History{max: max, path: path}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		h.remember(scanner.Text())
+	}
+	return h, scanner.Err()
+}
+
+// Entries returns the remembered lines, oldest first
+func (h *History) Entries() []string {
+	return h.entries
+}
+
+// Add remembers a line.  Blank lines and repeats of the last line are ignored.
+func (h *History) Add(line string) error {
+	if strings.TrimSpace(line) == "" || strings.ContainsAny(line, "\r\n") {
+		return nil
+	}
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return nil
+	}
+	h.remember(line)
+	if h.path == "" {
+		return nil
+	}
+	if h.max > 0 && len(h.entries) == h.max {
+		return h.Save()
+	}
+	file, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = file.WriteString(line + "\n")
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Save rewrites the history file with the remembered lines
+func (h *History) Save() error {
+	if h.path == "" {
+		return nil
+	}
+	return os.WriteFile(h.path, []byte(strings.Join(append(h.entries, ""), "\n")), 0600)
+}
+
+// remember adds a line to memory, dropping the oldest line when full
+func (h *History) remember(line string) {
+	h.entries = append(h.entries, line)
+	if h.max > 0 && len(h.entries) > h.max {
+		h.entries = h.entries[len(h.entries)-h.max:]
+	}
+}
+
+// search returns the index of the newest entry before index containing text, or -1
+func (h *History) search(text string, index int) int {
+	for i := min(index, len(h.entries)) - 1; i >= 0; i-- {
+		if strings.Contains(h.entries[i], text) {
+			return i
+		}
+	}
+	return -1
+}
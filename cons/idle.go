@@ -0,0 +1,214 @@
+package cons
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrIdleTimeout is returned by GetKey when no key is pressed within the time set by SetIdleCancel, so a form
+// or menu left waiting for input is canceled.  The next key pressed is not lost; it is returned by the next read.
+var ErrIdleTimeout = errors.New("cons: idle timeout")
+
+const (
+	// ScreenSaverNone shows no screen saver
+	ScreenSaverNone = iota
+	// ScreenSaverBlank blanks the screen and shows a moving clock
+	ScreenSaverBlank
+	// ScreenSaverDim dims the screen and shows a moving clock
+	ScreenSaverDim
+)
+
+// saverTick is how often the screen saver clock moves
+var saverTick = time.Second
+
+// clockFormat is the time shown by the screen saver
+const clockFormat = "15:04:05"
+
+var (
+	idleAfter   time.Duration
+	idleFunc    func()
+	saverAfter  time.Duration
+	saverStyle  = ScreenSaverNone
+	cancelAfter time.Duration
+)
+
+// SetIdleTimeout calls f when GetKey has waited after without a key being pressed.  f is called once for each
+// wait, on the goroutine reading keys, so it may draw.  A zero duration or nil f turns it off.
+func SetIdleTimeout(after time.Duration, f func()) {
+	idleAfter, idleFunc = after, f
+}
+
+// SetScreenSaver shows a screen saver in style when GetKey has waited after without a key being pressed.
+// The next key restores the screen and is otherwise ignored.  Screens that cannot be captured, such as the
+// console outside Windows, show none.  ScreenSaverNone or a zero duration turns it off.
+func SetScreenSaver(after time.Duration, style int) {
+	saverAfter, saverStyle = after, style
+}
+
+// SetIdleCancel makes GetKey return ErrIdleTimeout when it has waited after without a key being pressed.
+// A zero duration turns it off.
+func SetIdleCancel(after time.Duration) {
+	cancelAfter = after
+}
+
+// idleWait tracks the idle timeouts of one wait for a key
+type idleWait struct {
+	start    time.Time
+	called   bool
+	failed   bool
+	saved    *Snapshot
+	backdrop *Snapshot
+	colors   [2]int8
+	clock    [2]int
+	step     [2]int
+	ticked   time.Time
+	timer    *time.Timer
+}
+
+// startIdle starts timing a wait for a key
+func startIdle() *idleWait {
+	w := &idleWait{start: time.Now(), step: [2]int{1, 1}}
+	w.schedule()
+	return w
+}
+
+// saving returns true while the screen saver is shown
+func (w *idleWait) saving() bool {
+	return w.saved != nil
+}
+
+// schedule sets the timer for the next timeout, or stops it if none is left
+func (w *idleWait) schedule() {
+	var next time.Time
+	due := func(t time.Time) {
+		if next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+	if idleFunc != nil && idleAfter > 0 && !w.called {
+		due(w.start.Add(idleAfter))
+	}
+	if saverStyle != ScreenSaverNone && saverAfter > 0 && !w.saving() && !w.failed {
+		due(w.start.Add(saverAfter))
+	}
+	if w.saving() {
+		due(w.ticked.Add(saverTick))
+	}
+	if cancelAfter > 0 {
+		due(w.start.Add(cancelAfter))
+	}
+	w.stop()
+	if !next.IsZero() {
+		w.timer = time.NewTimer(time.Until(next))
+	}
+}
+
+// stop stops the timer
+func (w *idleWait) stop() {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+}
+
+// expired returns a channel that receives when the next timeout is due, or nil if there is none
+func (w *idleWait) expired() <-chan time.Time {
+	if w.timer == nil {
+		return nil
+	}
+	return w.timer.C
+}
+
+// fire handles the timeouts that are due.  It returns ErrIdleTimeout when the wait is canceled.
+func (w *idleWait) fire() error {
+	elapsed := time.Since(w.start)
+	if cancelAfter > 0 && elapsed >= cancelAfter {
+		w.wake()
+		return ErrIdleTimeout
+	}
+	if idleFunc != nil && idleAfter > 0 && !w.called && elapsed >= idleAfter {
+		w.called = true
+		idleFunc()
+	}
+	switch {
+	case w.saving() && time.Since(w.ticked) >= saverTick:
+		w.moveClock()
+	case !w.saving() && !w.failed && saverStyle != ScreenSaverNone && saverAfter > 0 && elapsed >= saverAfter:
+		w.failed = !w.showSaver()
+	}
+	w.schedule()
+	return nil
+}
+
+// showSaver saves the screen and draws the screen saver over it.  It returns false if the screen cannot be
+// captured to restore it afterward.
+func (w *idleWait) showSaver() bool {
+	screenMu.Lock()
+	defer screenMu.Unlock()
+	snap, err := screen.Capture()
+	if err != nil || snap.Rows == 0 || snap.Cols == 0 {
+		return false
+	}
+	fg, bg, err := screen.Color()
+	if err != nil {
+		return false
+	}
+	w.saved, w.colors = snap, [2]int8{fg, bg}
+	w.backdrop = &Snapshot{Rows: snap.Rows, Cols: snap.Cols, Cells: make([][]Cell, snap.Rows)}
+	for r, cells := range snap.Cells {
+		w.backdrop.Cells[r] = make([]Cell, len(cells))
+		for c, cell := range cells {
+			if saverStyle == ScreenSaverDim && cell.Char != ' ' {
+				w.backdrop.Cells[r][c] = Cell{Char: cell.Char, Foreground: ColorGray, Background: ColorBlack}
+			} else {
+				w.backdrop.Cells[r][c] = Cell{Char: ' ', Foreground: ColorGray, Background: ColorBlack}
+			}
+		}
+	}
+	restoreArea(w.backdrop, 0, 0, snap.Rows, snap.Cols)
+	w.clock = [2]int{snap.Rows / 2, max(0, (snap.Cols-len(clockFormat))/2)}
+	w.drawClock()
+	return true
+}
+
+// moveClock moves the clock a step, bouncing off the edges of the screen, and redraws it
+func (w *idleWait) moveClock() {
+	screenMu.Lock()
+	defer screenMu.Unlock()
+	restoreArea(w.backdrop, w.clock[0], w.clock[1], 1, len(clockFormat))
+	limits := [2]int{w.backdrop.Rows - 1, max(0, w.backdrop.Cols-len(clockFormat))}
+	for i := range w.clock {
+		if w.clock[i]+w.step[i] < 0 || w.clock[i]+w.step[i] > limits[i] {
+			w.step[i] = -w.step[i]
+		}
+		w.clock[i] = max(0, min(w.clock[i]+w.step[i], limits[i]))
+	}
+	w.drawClock()
+}
+
+// drawClock draws the time at the clock position.  screenMu must be held.
+func (w *idleWait) drawClock() {
+	w.ticked = time.Now()
+	screen.SetColor(ColorBrightWhite, ColorBlack)
+	screen.Locate(w.clock[0], w.clock[1])
+	screen.Write([]byte(fitText(w.ticked.Format(clockFormat), min(len(clockFormat), w.backdrop.Cols))))
+}
+
+// wake restores the screen if the screen saver is shown and returns true if it was.  Updates posted while it
+// was shown are drawn after the screen is restored.
+func (w *idleWait) wake() bool {
+	if !w.saving() {
+		return false
+	}
+	screenMu.Lock()
+	restoreArea(w.saved, 0, 0, w.saved.Rows, w.saved.Cols)
+	screen.SetColor(w.colors[0], w.colors[1])
+	screen.Locate(w.saved.CursorRow, w.saved.CursorCol)
+	screenMu.Unlock()
+	w.saved, w.backdrop = nil, nil
+	w.start = time.Now()
+	w.called = false
+	w.schedule()
+	RunPosted()
+	return true
+}
@@ -0,0 +1,71 @@
+package cons
+
+import (
+	"errors"
+	"io"
+)
+
+// KeySource supplies key events to GetKey and Inkey
+type KeySource interface {
+	// GetKey waits for a key.  io.EOF means the source has no more keys.
+	GetKey() (KeyEvent, error)
+	// Inkey returns a key if one is ready or a 0 key if not, without waiting
+	Inkey() (KeyEvent, error)
+}
+
+// keyboard reads keys from the console
+type keyboard struct{}
+
+func (keyboard) GetKey() (KeyEvent, error) {
+	return sysGetKey()
+}
+
+func (keyboard) Inkey() (KeyEvent, error) {
+	return sysInkey()
+}
+
+var keySource KeySource = keyboard{}
+
+// SetKeySource makes GetKey and Inkey read from src, i.e. a Replay.  When src runs out of keys
+// input returns to the keyboard.  nil returns to the keyboard immediately.  A key still being read from the
+// prior source after an idle timeout is kept, and returned once that source is set again.
+func SetKeySource(src KeySource) {
+	if src == nil {
+		src = keyboard{}
+	}
+	keySource = src
+	pruneReads()
+}
+
+// readKey reads a key from the key source, waiting for it if wait is true, and records it.
+// Updates posted by other goroutines are drawn while waiting.
+func readKey(wait bool) (KeyEvent, error) {
+	for {
+		var key KeyEvent
+		var err error
+		if wait {
+			key, err = waitKey()
+		} else if pending := pendingKey(keySource); pending != nil {
+			select {
+			case r := <-pending:
+				endRead(pending)
+				key, err = r.key, r.err
+			default:
+				RunPosted()
+			}
+		} else {
+			RunPosted()
+			key, err = keySource.Inkey()
+		}
+		if errors.Is(err, io.EOF) {
+			if _, ok := keySource.(keyboard); !ok {
+				keySource = keyboard{}
+				continue
+			}
+		}
+		if err == nil && key.Key != 0 && recorder != nil {
+			recorder.record(key)
+		}
+		return key, err
+	}
+}
@@ -0,0 +1,94 @@
+package cons
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// ErrInterrupted is returned when input is ended by Control+C
+var ErrInterrupted = errors.New("cons: interrupted")
+
+// interruptHandler is read by the signal goroutine started by HandleSignals
+var interruptHandler atomic.Pointer[func() bool]
+
+// SetInterruptHandler registers a function called when Control+C is pressed during input, i.e. to confirm quitting.
+// Returning true continues input as if the key was not pressed, false interrupts it.  nil removes the handler.
+// After HandleSignals, Control+C outside of input calls the handler on the goroutine receiving the signal, so a
+// handler that draws should do so with Post.
+func SetInterruptHandler(handler func() bool) {
+	if handler == nil {
+		interruptHandler.Store(nil)
+	} else {
+		interruptHandler.Store(&handler)
+	}
+}
+
+// interrupted asks the interrupt handler whether Control+C should end input
+func interrupted() bool {
+	handler := interruptHandler.Load()
+	return handler == nil || !(*handler)()
+}
+
+// Exit restores the console and ends the program with the status code
+func Exit(code int) {
+	Restore()
+	os.Exit(code)
+}
+
+// terminate ends the program after Control+C in a function that cannot return ErrInterrupted
+func terminate() {
+	Restore()
+	fmt.Println("^C")
+	os.Exit(130)
+}
+
+// fail ends the program after an error in a function that cannot return it
+func fail(err error) {
+	if errors.Is(err, ErrInterrupted) {
+		terminate()
+	}
+	Restore()
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
+
+// HandleSignals restores the console when the program is ended by a signal.  SIGINT, sent for Control+C
+// outside of input, asks the handler set by SetInterruptHandler, which is called on the goroutine receiving
+// the signal; if it continues the signal is ignored.  Otherwise, and for SIGTERM, the console is restored and
+// the program ends with status 130 or 143.  On Windows closing the console window, logging off or shutting
+// down is delivered as SIGTERM.  Programs that handle signals themselves should not call it.  The returned
+// function stops handling signals.
+func HandleSignals() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for {
+			select {
+			case sig := <-signals:
+				if sig == os.Interrupt && !interrupted() {
+					continue
+				}
+				Restore()
+				if sig == os.Interrupt {
+					os.Exit(130)
+				}
+				os.Exit(143)
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package cons
+
+import (
+	"fmt"
+	"unicode"
+)
+
+// Action is a named editing action that a key can be bound to
+type Action int
+
+const (
+	// ActionNone means the key is not bound and is treated as a typed character
+	ActionNone Action = iota
+	// ActionAccept accepts the field and moves to the next one.  On the last field it saves.
+	ActionAccept
+	// ActionNextField moves to the next field, wrapping to the first
+	ActionNextField
+	// ActionPrevField moves to the prior field, wrapping to the last
+	ActionPrevField
+	// ActionFirstField moves to the first field
+	ActionFirstField
+	// ActionLastField moves to the last field
+	ActionLastField
+	// ActionLeft moves left a character
+	ActionLeft
+	// ActionRight moves right a character
+	ActionRight
+	// ActionWordLeft moves left a word
+	ActionWordLeft
+	// ActionWordRight moves right a word
+	ActionWordRight
+	// ActionHome moves to the beginning of the field
+	ActionHome
+	// ActionEnd moves to the end of the entered text
+	ActionEnd
+	// ActionBackspace deletes the character to the left of the cursor
+	ActionBackspace
+	// ActionDeleteChar deletes the character under the cursor
+	ActionDeleteChar
+	// ActionDeleteToEnd deletes from the cursor to the end of the field
+	ActionDeleteToEnd
+	// ActionClearField deletes the whole field
+	ActionClearField
+	// ActionUndo undoes the last change to the field
+	ActionUndo
+	// ActionRedo redoes the last change that was undone
+	ActionRedo
+	// ActionRevertField restores the value the field had when entry started
+	ActionRevertField
+	// ActionCut moves the selection, or the whole field, to the clipboard
+	ActionCut
+	// ActionCopy copies the selection, or the whole field, to the clipboard
+	ActionCopy
+	// ActionPaste replaces the selection with the clipboard
+	ActionPaste
+	// ActionToggleInsert switches between insert and overwrite
+	ActionToggleInsert
+	// ActionKillWordLeft cuts the word left of the cursor
+	ActionKillWordLeft
+	// ActionKillToStart cuts from the start of the line to the cursor
+	ActionKillToStart
+	// ActionHistoryPrev recalls the prior history line
+	ActionHistoryPrev
+	// ActionHistoryNext recalls the next history line
+	ActionHistoryNext
+	// ActionHistorySearch searches history for the text typed next
+	ActionHistorySearch
+	// ActionComplete completes the word left of the cursor
+	ActionComplete
+	// ActionInsertSpace inserts a space at the cursor
+	ActionInsertSpace
+	// ActionSave exits entry with success
+	ActionSave
+	// ActionCancel exits entry with failure
+	ActionCancel
+	// ActionInterrupt is the Control+C interrupt
+	ActionInterrupt
+	// ActionPopup opens the field's popup, i.e. the date picker of a date field
+	ActionPopup
+	// ActionNextPage moves to the next page of a TabSet
+	ActionNextPage
+	// ActionPrevPage moves to the prior page of a TabSet
+	ActionPrevPage
+	// ActionExit ends entry so the caller can handle the key, i.e. Alt+letter to choose a tab
+	ActionExit
+)
+
+// KeyMap maps key events (key + modifiers) to editing actions
+type KeyMap map[KeyEvent]Action
+
+// Key returns the key event for key with the modifiers mods
+func Key(key uint8, mods int8) KeyEvent {
+	return KeyEvent{Key: key, Modifier: mods}
+}
+
+// RuneKey returns the key event for typing a character, i.e. RuneKey('é').  Characters outside ASCII are
+// KeyRune events.
+func RuneKey(r rune) KeyEvent {
+	if r >= 0 && r < 128 {
+		return KeyEvent{Key: uint8(r)}
+	}
+	return KeyEvent{Key: KeyRune, Rune: r}
+}
+
+// Ctrl returns the key event for Control plus a letter, i.e. Ctrl('Z')
+func Ctrl(letter byte) KeyEvent {
+	return KeyEvent{Key: letter & 0x1f, Modifier: KeyControl}
+}
+
+// Alt returns the key event for Alt plus a letter, i.e. Alt('f')
+func Alt(letter byte) KeyEvent {
+	return KeyEvent{Key: letter, Modifier: KeyAlt}
+}
+
+// DefaultKeyMap returns the standard bindings used by StartEntry
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		Key(KeyEnter, 0):                 ActionAccept,
+		Key(KeyDown, 0):                  ActionNextField,
+		Key(KeyTab, 0):                   ActionNextField,
+		Key(KeyTab, KeyShift):            ActionPrevField,
+		Key(KeyUp, 0):                    ActionPrevField,
+		Key(KeyHome, KeyControl):         ActionFirstField,
+		Key(KeyEnd, KeyControl):          ActionLastField,
+		Key(KeyLeft, 0):                  ActionLeft,
+		Key(KeyRight, 0):                 ActionRight,
+		Key(KeyLeft, KeyControl):         ActionWordLeft,
+		Key(KeyRight, KeyControl):        ActionWordRight,
+		Key(KeyHome, 0):                  ActionHome,
+		Key(KeyEnd, 0):                   ActionEnd,
+		Key(KeyBackspace, 0):             ActionBackspace,
+		Key(KeyDel, 0):                   ActionDeleteChar,
+		Key(KeyDel, KeyControl):          ActionDeleteToEnd,
+		Key(KeyIns, 0):                   ActionInsertSpace,
+		Ctrl('Z'):                        ActionUndo,
+		Ctrl('Y'):                        ActionRedo,
+		Ctrl('R'):                        ActionRevertField,
+		Ctrl('X'):                        ActionCut,
+		Key(KeyDel, KeyShift):            ActionCut,
+		Key(KeyIns, KeyControl):          ActionCopy,
+		Ctrl('V'):                        ActionPaste,
+		Key(KeyIns, KeyShift):            ActionPaste,
+		Key(KeyF10, 0):                   ActionSave,
+		Key(KeyControlEnter, 0):          ActionSave,
+		Key(KeyControlEnter, KeyControl): ActionSave,
+		Key(KeyEscape, 0):                ActionCancel,
+		Ctrl('C'):                        ActionInterrupt,
+		Key(KeyF4, 0):                    ActionPopup,
+		Key(KeyDown, KeyAlt):             ActionPopup,
+	}
+}
+
+// DefaultLineKeyMap returns the standard bindings used by LineInput and LineEditor
+func DefaultLineKeyMap() KeyMap {
+	return KeyMap{
+		Key(KeyEnter, 0):          ActionAccept,
+		Key(KeyEscape, 0):         ActionCancel,
+		Ctrl('C'):                 ActionInterrupt,
+		Key(KeyLeft, 0):           ActionLeft,
+		Key(KeyRight, 0):          ActionRight,
+		Ctrl('B'):                 ActionLeft,
+		Ctrl('F'):                 ActionRight,
+		Key(KeyLeft, KeyControl):  ActionWordLeft,
+		Key(KeyRight, KeyControl): ActionWordRight,
+		Key(KeyHome, 0):           ActionHome,
+		Key(KeyEnd, 0):            ActionEnd,
+		Ctrl('A'):                 ActionHome,
+		Ctrl('E'):                 ActionEnd,
+		Key(KeyIns, 0):            ActionToggleInsert,
+		Key(KeyBackspace, 0):      ActionBackspace,
+		Key(KeyDel, 0):            ActionDeleteChar,
+		Ctrl('W'):                 ActionKillWordLeft,
+		Ctrl('U'):                 ActionKillToStart,
+		Ctrl('K'):                 ActionDeleteToEnd,
+		Key(KeyDel, KeyControl):   ActionDeleteToEnd,
+		Ctrl('Y'):                 ActionPaste,
+		Key(KeyIns, KeyShift):     ActionPaste,
+		Key(KeyUp, 0):             ActionHistoryPrev,
+		Key(KeyDown, 0):           ActionHistoryNext,
+		Ctrl('P'):                 ActionHistoryPrev,
+		Ctrl('N'):                 ActionHistoryNext,
+		Ctrl('R'):                 ActionHistorySearch,
+		Key(KeyTab, 0):            ActionComplete,
+	}
+}
+
+// WordStarKeyMap returns the default bindings plus the WordStar control key diamond
+func WordStarKeyMap() KeyMap {
+	return DefaultKeyMap().With(KeyMap{
+		Ctrl('E'): ActionPrevField,
+		Ctrl('X'): ActionNextField,
+		Ctrl('S'): ActionLeft,
+		Ctrl('D'): ActionRight,
+		Ctrl('A'): ActionWordLeft,
+		Ctrl('F'): ActionWordRight,
+		Ctrl('G'): ActionDeleteChar,
+		Ctrl('Y'): ActionClearField,
+		Ctrl('V'): ActionInsertSpace,
+	})
+}
+
+// EmacsKeyMap returns the default bindings plus Emacs style movement and kill keys
+func EmacsKeyMap() KeyMap {
+	return DefaultKeyMap().With(KeyMap{
+		Ctrl('P'): ActionPrevField,
+		Ctrl('N'): ActionNextField,
+		Ctrl('B'): ActionLeft,
+		Ctrl('F'): ActionRight,
+		Alt('b'):  ActionWordLeft,
+		Alt('f'):  ActionWordRight,
+		Ctrl('A'): ActionHome,
+		Ctrl('E'): ActionEnd,
+		Ctrl('D'): ActionDeleteChar,
+		Ctrl('K'): ActionDeleteToEnd,
+		Ctrl('W'): ActionCut,
+		Alt('w'):  ActionCopy,
+		Ctrl('Y'): ActionPaste,
+		Ctrl('_'): ActionUndo,
+		Ctrl('G'): ActionCancel,
+	})
+}
+
+// With returns a copy of the key map with overrides applied.  Binding a key to ActionNone removes it.
+func (m KeyMap) With(overrides KeyMap) KeyMap {
+	out := make(KeyMap, len(m)+len(overrides))
+	for k, a := range m {
+		out[k] = a
+	}
+	for k, a := range overrides {
+		if a == ActionNone {
+			delete(out, k)
+		} else {
+			out[k] = a
+		}
+	}
+	return out
+}
+
+// Action returns the action bound to a key event.  Caps lock is ignored and a shifted
+// key that has no binding of its own falls back to its unshifted binding.
+func (m KeyMap) Action(key KeyEvent) Action {
+	key.Modifier &^= KeyCapsLock
+	if a, ok := m[key]; ok {
+		return a
+	}
+	if key.Modifier&KeyShift != 0 && !isPrintable(key) {
+		key.Modifier &^= KeyShift
+		return m[key]
+	}
+	return ActionNone
+}
+
+// isPrintable returns true if the key event is a typed character rather than a control key
+func isPrintable(key KeyEvent) bool {
+	return key.Key >= ' ' && key.Key < 127 || key.Key == KeyRune && unicode.IsPrint(key.Rune)
+}
+
+// keyRune returns the character typed for a key event
+func keyRune(key KeyEvent) rune {
+	if key.Key == KeyRune {
+		return key.Rune
+	}
+	return rune(key.Key)
+}
+
+var keyMap = DefaultKeyMap()
+var lineKeyMap = DefaultLineKeyMap()
+
+// GetKeyMap returns the key map used by StartEntry
+func GetKeyMap() KeyMap {
+	return keyMap
+}
+
+// SetKeyMap changes the key map used by StartEntry.  nil restores the default.
+func SetKeyMap(m KeyMap) {
+	if m == nil {
+		m = DefaultKeyMap()
+	}
+	keyMap = m
+}
+
+// GetLineKeyMap returns the key map used by LineInput and LineEditor
+func GetLineKeyMap() KeyMap {
+	return lineKeyMap
+}
+
+// SetLineKeyMap changes the key map used by LineInput and LineEditor.  nil restores the default.
+func SetLineKeyMap(m KeyMap) {
+	if m == nil {
+		m = DefaultLineKeyMap()
+	}
+	lineKeyMap = m
+}
+
+// KeyFor returns the key bound to an action, preferring function keys, then named keys like Esc, then
+// Control and Alt keys.  It returns false if no key is bound to the action.
+func (m KeyMap) KeyFor(action Action) (KeyEvent, bool) {
+	var best KeyEvent
+	found := false
+	for key, a := range m {
+		if a == action && (!found || keyRank(key) < keyRank(best) ||
+			keyRank(key) == keyRank(best) && (key.Key < best.Key || key.Key == best.Key && key.Modifier < best.Modifier)) {
+			best, found = key, true
+		}
+	}
+	return best, found
+}
+
+// keyRank orders keys by how well they read in a legend
+func keyRank(key KeyEvent) int {
+	rank := 2
+	switch {
+	case key.Key >= KeyF1 && key.Key <= KeyF24:
+		rank = 0
+	case key.Key == KeyEscape || key.Key == KeyEnter || key.Key == KeyTab || key.Key > 127:
+		rank = 1
+	}
+	if key.Modifier&^KeyCapsLock != 0 {
+		rank += 3
+	}
+	return rank
+}
+
+// keyNames are the names of keys that are not printable characters
+var keyNames = map[uint8]string{
+	KeyEnter: "Enter", KeyControlEnter: "Ctrl+Enter", KeyTab: "Tab", KeyBackspace: "Backspace", KeyEscape: "Esc",
+	KeyPageUp: "PgUp", KeyPageDown: "PgDn", KeyEnd: "End", KeyHome: "Home", KeyLeft: "Left", KeyUp: "Up",
+	KeyRight: "Right", KeyDown: "Down", KeyIns: "Ins", KeyDel: "Del", ' ': "Space",
+}
+
+// KeyName returns the name of a key event as shown in legends, i.e. "F10", "Esc", "Ctrl+Z" or "Alt+F"
+func KeyName(key KeyEvent) string {
+	prefix := ""
+	if key.Modifier&KeyControl != 0 && key.Key != KeyControlEnter {
+		prefix += "Ctrl+"
+	}
+	if key.Modifier&KeyAlt != 0 {
+		prefix += "Alt+"
+	}
+	if key.Modifier&KeyShift != 0 && !isPrintable(key) {
+		prefix += "Shift+"
+	}
+	if name, ok := keyNames[key.Key]; ok {
+		return prefix + name
+	}
+	switch {
+	case key.Key >= KeyF1 && key.Key <= KeyF24:
+		return fmt.Sprintf("%sF%d", prefix, key.Key-KeyF1+1)
+	case key.Key < ' ':
+		if key.Modifier&KeyControl == 0 {
+			prefix += "Ctrl+"
+		}
+		return prefix + string(rune(key.Key+'@'))
+	case key.Modifier&(KeyControl|KeyAlt) != 0 && key.Key >= 'a' && key.Key <= 'z':
+		return prefix + string(rune(key.Key-'a'+'A'))
+	}
+	return prefix + string(keyRune(key))
+}
@@ -0,0 +1,198 @@
+package cons
+
+const (
+	// LayoutVertical stacks widgets top to bottom
+	LayoutVertical = iota
+	// LayoutHorizontal places widgets left to right
+	LayoutHorizontal
+	// LayoutGrid places widgets in rows of Columns equal cells
+	LayoutGrid
+)
+
+// Container is a widget that lays out other widgets in its area, with an optional border and title.  Widgets
+// that want a size get it when there is room and the rest share what is left.
+type Container struct {
+	Base
+	// Layout is LayoutVertical, LayoutHorizontal or LayoutGrid
+	Layout int
+	// Columns is the number of cells in each row of a grid
+	Columns int
+	// Spacing is the number of blank rows or columns between widgets
+	Spacing int
+	// Border is the line style drawn around the widgets
+	Border int
+	// Title is drawn in the top border
+	Title string
+	// Foreground and Background are the border colors.  A bordered container is cleared to them.
+	Foreground int8
+	Background int8
+	Widgets    []Widget
+}
+
+// NewVBox creates a container that stacks widgets top to bottom
+func NewVBox(widgets ...Widget) *Container {
+	return &Container{Layout: LayoutVertical, Border: LineStyleNone, Foreground: ColorWhite, Background: ColorBlue,
+		Widgets: widgets}
+}
+
+// NewHBox creates a container that places widgets left to right
+func NewHBox(widgets ...Widget) *Container {
+	c := NewVBox(widgets...)
+	c.Layout = LayoutHorizontal
+	return c
+}
+
+// NewGrid creates a container that places widgets in rows of columns equal cells
+func NewGrid(columns int, widgets ...Widget) *Container {
+	c := NewVBox(widgets...)
+	c.Layout = LayoutGrid
+	c.Columns = columns
+	return c
+}
+
+// Add appends widgets to the container and returns it
+func (c *Container) Add(widgets ...Widget) *Container {
+	c.Widgets = append(c.Widgets, widgets...)
+	return c
+}
+
+// Children returns the widgets of the container
+func (c *Container) Children() []Widget {
+	return c.Widgets
+}
+
+// frame returns the width of the border on each side
+func (c *Container) frame() int {
+	if c.Border == LineStyleNone {
+		return 0
+	}
+	return 1
+}
+
+// Size returns the space needed by widgets that all want a size, or zero in a direction where one does not
+func (c *Container) Size() (int, int) {
+	if len(c.Widgets) == 0 || c.Layout == LayoutGrid {
+		return 0, 0
+	}
+	along, across := 0, 0
+	flexAlong, flexAcross := false, false
+	for _, w := range c.Widgets {
+		rows, cols := w.Size()
+		if c.Layout == LayoutHorizontal {
+			rows, cols = cols, rows
+		}
+		along += rows
+		across = max(across, cols)
+		flexAlong = flexAlong || rows == 0
+		flexAcross = flexAcross || cols == 0
+	}
+	along += c.Spacing * (len(c.Widgets) - 1)
+	if flexAlong {
+		along = 0
+	}
+	if flexAcross {
+		across = 0
+	}
+	if along > 0 {
+		along += 2 * c.frame()
+	}
+	if across > 0 {
+		across += 2 * c.frame()
+	}
+	if c.Layout == LayoutHorizontal {
+		return across, along
+	}
+	return along, across
+}
+
+// Place sets the container's area and lays out its widgets in it
+func (c *Container) Place(row int, col int, rows int, cols int) {
+	c.Base.Place(row, col, rows, cols)
+	f := c.frame()
+	row, col, rows, cols = row+f, col+f, max(0, rows-2*f), max(0, cols-2*f)
+	switch c.Layout {
+	case LayoutGrid:
+		columns := max(1, c.Columns)
+		lines := (len(c.Widgets) + columns - 1) / columns
+		heights := share(rows, c.Spacing, make([]int, lines))
+		widths := share(cols, c.Spacing, make([]int, columns))
+		top := row
+		for line := 0; line < lines; line++ {
+			left := col
+			for column := 0; column < columns && line*columns+column < len(c.Widgets); column++ {
+				c.Widgets[line*columns+column].Place(top, left, heights[line], widths[column])
+				left += widths[column] + c.Spacing
+			}
+			top += heights[line] + c.Spacing
+		}
+	case LayoutHorizontal:
+		wants := make([]int, len(c.Widgets))
+		for i, w := range c.Widgets {
+			_, wants[i] = w.Size()
+		}
+		left := col
+		for i, width := range share(cols, c.Spacing, wants) {
+			height, _ := c.Widgets[i].Size()
+			if height == 0 || height > rows {
+				height = rows
+			}
+			c.Widgets[i].Place(row, left, height, width)
+			left += width + c.Spacing
+		}
+	default:
+		wants := make([]int, len(c.Widgets))
+		for i, w := range c.Widgets {
+			wants[i], _ = w.Size()
+		}
+		top := row
+		for i, height := range share(rows, c.Spacing, wants) {
+			_, width := c.Widgets[i].Size()
+			if width == 0 || width > cols {
+				width = cols
+			}
+			c.Widgets[i].Place(top, col, height, width)
+			top += height + c.Spacing
+		}
+	}
+}
+
+// share divides space between items that want a size, or zero for a share of what is left, with spacing
+// between them.  Items that want more than is left get what is left.
+func share(space int, spacing int, wants []int) []int {
+	sizes := make([]int, len(wants))
+	left := space - spacing*max(0, len(wants)-1)
+	flexible := 0
+	for i, want := range wants {
+		if want == 0 {
+			flexible++
+			continue
+		}
+		sizes[i] = max(0, min(want, left))
+		left -= sizes[i]
+	}
+	for i, want := range wants {
+		if want == 0 {
+			sizes[i] = max(0, left/flexible)
+			left -= sizes[i]
+			flexible--
+		}
+	}
+	return sizes
+}
+
+// Draw draws the border and the widgets
+func (c *Container) Draw() {
+	if c.Border != LineStyleNone && c.Rows >= 2 && c.Cols >= 2 {
+		w := Window{Row: c.Row, Col: c.Col, Rows: c.Rows, Cols: c.Cols, Border: c.Border, Title: c.Title,
+			Foreground: c.Foreground, Background: c.Background}
+		w.Draw()
+	}
+	for _, w := range c.Widgets {
+		w.Draw()
+	}
+}
+
+// HandleEvent uses no keys.  The view sends keys to the focused widget in the container.
+func (c *Container) HandleEvent(key KeyEvent) (bool, error) {
+	return false, nil
+}
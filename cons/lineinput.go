@@ -1,81 +1,475 @@
 package cons
 
-import "fmt"
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// LineEditor edits a line of input at the cursor position
+type LineEditor struct {
+	// Max is the most characters that may be entered, or 0 for no limit
+	Max int
+	// History is navigated with up/down and searched with control+r.  nil disables history.
+	History *History
+	// Complete returns the completions of the word ending at pos in line.  nil disables completion.
+	Complete func(line string, pos int) []string
+	// Keys is the key map to use.  nil uses the key map set by SetLineKeyMap.
+	Keys KeyMap
+	// Secret hides the line.  Each character is echoed as Mask, or nothing if Mask is 0.
+	// A secret line is never added to history or the clipboard and the edit buffer is wiped after it is returned.
+	// Read it with ReadBytes or InputBytes so the caller can zero it; Read returns a string that cannot be wiped.
+	Secret bool
+	// Mask is the character echoed for each character of a Secret line
+	Mask rune
+	// Value is the text the line starts with
+	Value string
+
+	buf      []rune
+	pos      int
+	insert   bool
+	row      int
+	col      int
+	scroll   int
+	drawn    int
+	below    int
+	index    int
+	saved    string
+	prompt   string
+	search   bool
+	query    []rune
+	searchAt int
+}
 
 // LineInputLen allows entry of up to max characters
 func LineInputLen(max int) string {
-	var ch KeyEvent
-	var entry string
+	editor := LineEditor{Max: max}
+	return editor.Input()
+}
+
+// LineInput allows entry of a line
+func LineInput() string {
+	var editor LineEditor
+	return editor.Input()
+}
+
+// SecretInput allows entry of a password or PIN of up to max characters, echoing mask for each character.
+// A mask of 0 echoes nothing.  The caller should zero the returned bytes once it is done with them.
+func SecretInput(mask rune, max int) []byte {
+	editor := LineEditor{Max: max, Secret: true, Mask: mask}
+	return editor.InputBytes()
+}
+
+// NewLineEditor creates a line editor with history and tab completion
+func NewLineEditor(history *History, complete func(line string, pos int) []string) *LineEditor {
+	return &LineEditor{History: history, Complete: complete}
+}
+
+// Input reads a line starting at the cursor.  Escape, or waiting longer than the time set by SetIdleCancel,
+// returns an empty string.  With the default line key map:
+// left/right move a character, control+left/right move a word, and home/end (or control+a/e) move to the ends.
+// insert toggles between insert and overwrite.
+// backspace and delete delete a character.  control+w kills the word left of the cursor, control+u kills to the start
+// and control+k kills to the end of the line.  control+y pastes the last kill.
+// up/down recall history, control+r searches history, and tab completes the word at the cursor.
+// control+c restores the console and ends the program unless the handler set by SetInterruptHandler continues input.
+func (e *LineEditor) Input() string {
+	line, err := e.Read()
+	if err != nil && !errors.Is(err, ErrIdleTimeout) {
+		terminate()
+	}
+	return line
+}
+
+// Read reads a line like Input.  Control+C returns ErrInterrupted unless the interrupt handler continues input.
+func (e *LineEditor) Read() (string, error) {
+	if ok, err := e.edit(); !ok {
+		return "", err
+	}
+	line := string(e.buf)
+	e.wipe()
+	return line, nil
+}
+
+// InputBytes reads a line like Input but returns it as UTF-8 bytes, which the caller can zero after use.
+// Escape returns nil.
+func (e *LineEditor) InputBytes() []byte {
+	line, err := e.ReadBytes()
+	if err != nil && !errors.Is(err, ErrIdleTimeout) {
+		terminate()
+	}
+	return line
+}
+
+// ReadBytes reads a line like InputBytes.  Control+C returns ErrInterrupted unless the interrupt handler continues input.
+func (e *LineEditor) ReadBytes() ([]byte, error) {
+	if ok, err := e.edit(); !ok {
+		return nil, err
+	}
+	line := make([]byte, 0, len(e.buf)*utf8.UTFMax)
+	for _, r := range e.buf {
+		line = utf8.AppendRune(line, r)
+	}
+	e.wipe()
+	return line, nil
+}
+
+// edit runs the editor until the line is accepted, returning true, or cancelled or interrupted, returning false
+func (e *LineEditor) edit() (bool, error) {
+	if lineMode {
+		return e.editLine()
+	}
+	defer HoldScreen()()
+	keys := e.Keys
+	if keys == nil {
+		keys = lineKeyMap
+	}
+	e.buf = append(e.buf[:0], []rune(e.Value)...)
+	e.pos = len(e.buf)
+	e.insert = true
+	e.row = Row()
+	e.col = Col()
+	e.scroll = 0
+	e.drawn = 0
+	e.below = 0
+	e.prompt = ""
+	e.search = false
+	if e.History != nil {
+		e.index = len(e.History.entries)
+	}
+	if len(e.buf) > 0 {
+		e.draw()
+	}
 
 	for {
-		ch = GetKey()
-		if ch.Key == KeyEnter || ch.Key == KeyEscape {
-			break
+		ch, err := GetKeyErr()
+		if err != nil {
+			return false, err
+		}
+		e.clearCompletions()
+		action := keys.Action(ch)
+		if e.search {
+			if action = e.searchKey(ch, action); action == ActionNone {
+				e.locate()
+				continue
+			}
 		}
-		switch ch.Key {
-		case KeyControlC: // ^c
-			SetColor(ColorGray, ColorBlack) // Ensure we're at normal background
-			fmt.Print("^c")
-			panic("Terminating")
-
-		case KeyBackspace:
-			if len([]rune(entry)) > 0 {
-				fmt.Print("\b \b")
-				n := len(entry) - 1
-				entry = (entry)[:n]
+		switch action {
+		case ActionAccept:
+			e.pos = len(e.buf)
+			e.draw()
+			if e.History != nil && !e.Secret {
+				e.History.Add(string(e.buf))
+			}
+			return true, nil
+		case ActionCancel:
+			e.wipe()
+			return false, nil
+		case ActionInterrupt:
+			if interrupted() {
+				e.wipe()
+				return false, ErrInterrupted
+			}
+		case ActionLeft:
+			e.move(e.pos - 1)
+		case ActionRight:
+			e.move(e.pos + 1)
+		case ActionWordLeft:
+			e.move(wordLeft(e.buf, e.pos))
+		case ActionWordRight:
+			e.move(wordRight(e.buf, e.pos))
+		case ActionHome:
+			e.move(0)
+		case ActionEnd:
+			e.move(len(e.buf))
+		case ActionToggleInsert:
+			e.insert = !e.insert
+		case ActionBackspace:
+			if e.pos > 0 {
+				e.replace(e.pos-1, e.pos, nil)
 			} else {
 				Beep()
 			}
-			break
-		default:
-			if len([]rune(entry)) >= max {
+		case ActionDeleteChar:
+			if e.pos < len(e.buf) {
+				e.replace(e.pos, e.pos+1, nil)
+			}
+		case ActionKillWordLeft:
+			e.kill(wordLeft(e.buf, e.pos), e.pos)
+		case ActionKillToStart:
+			e.kill(0, e.pos)
+		case ActionDeleteToEnd:
+			e.kill(e.pos, len(e.buf))
+		case ActionClearField:
+			e.kill(0, len(e.buf))
+		case ActionPaste:
+			e.insertText([]rune(strings.NewReplacer("\r", "", "\n", " ", "\t", " ").Replace(clipboard)))
+		case ActionHistoryPrev:
+			e.recall(e.index - 1)
+		case ActionHistoryNext:
+			e.recall(e.index + 1)
+		case ActionHistorySearch:
+			if e.History == nil || e.Secret {
+				Beep()
+				break
+			}
+			e.saved = string(e.buf)
+			e.search = true
+			e.query = nil
+			e.searchAt = len(e.History.entries)
+			e.prompt = searchPrompt(e.query)
+			e.draw()
+		case ActionComplete:
+			e.complete()
+		case ActionNone:
+			if !isPrintable(ch) {
 				Beep()
-				break // ignore character
+				break
+			}
+			if e.insert || e.pos == len(e.buf) {
+				e.insertText([]rune{keyRune(ch)})
+			} else {
+				e.replace(e.pos, e.pos+1, []rune{keyRune(ch)})
+				e.move(e.pos + 1)
 			}
-			fmt.Print(string(ch.Key))
-			entry += string(ch.Key)
-			break
 		}
+		e.locate()
 	}
-	if ch.Key == 27 {
-		return ""
+}
+
+// locate puts the cursor at the edit position
+func (e *LineEditor) locate() {
+	if e.Secret && e.Mask == 0 {
+		Locate(e.row, e.col)
+		return
 	}
-	return entry
+	Locate(e.row, e.col+len([]rune(e.prompt))+e.pos-e.scroll)
 }
 
-// LineInput allows entry of a line
-func LineInput() string {
-	var ch KeyEvent
-	var entry string
+// wipe zeroes the line buffer so a secret does not stay in memory
+func (e *LineEditor) wipe() {
+	clear(e.buf[:cap(e.buf)])
+	e.buf = e.buf[:0]
+}
 
-	for {
-		ch = GetKey()
-		if ch.Key == KeyEnter || ch.Key == KeyEscape {
-			break
+// editLine reads the line from stdin in line mode.  The end of stdin cancels.
+func (e *LineEditor) editLine() (bool, error) {
+	line, err := readLine()
+	if err != nil {
+		return false, nil
+	}
+	e.buf = append(e.buf[:0], []rune(line)...)
+	if e.Max > 0 && len(e.buf) > e.Max {
+		e.buf = e.buf[:e.Max]
+	}
+	if e.History != nil && !e.Secret {
+		e.History.Add(string(e.buf))
+	}
+	return true, nil
+}
+
+// move moves the cursor to pos within the line
+func (e *LineEditor) move(pos int) {
+	e.pos = max(0, min(pos, len(e.buf)))
+	e.draw()
+}
+
+// replace replaces buf[start:end] with text, leaving the cursor at start
+func (e *LineEditor) replace(start int, end int, text []rune) {
+	rest := append([]rune(nil), e.buf[end:]...)
+	old := e.buf
+	e.buf = append(append(e.buf[:start], text...), rest...)
+	if e.Secret {
+		clear(rest)
+		if cap(old) != cap(e.buf) {
+			clear(old[:cap(old)])
 		}
-		switch ch.Key {
-		case KeyControlC: // ^c
-			SetColor(ColorGray, ColorBlack) // Ensure we're at normal background
-			fmt.Print("^c")
-			panic("Terminating")
-
-		case KeyBackspace:
-			if len([]rune(entry)) > 0 {
-				fmt.Print("\b \b")
-				n := len(entry) - 1
-				entry = (entry)[:n]
-			} else {
-				Beep()
-			}
-			break
-		default:
-			fmt.Print(string(ch.Key))
-			entry += string(ch.Key)
-			break
+	}
+	e.pos = start
+	e.draw()
+}
+
+// insertText inserts text at the cursor up to the maximum length
+func (e *LineEditor) insertText(text []rune) {
+	if e.Max > 0 {
+		text = text[:max(0, min(len(text), e.Max-len(e.buf)))]
+	}
+	if len(text) == 0 {
+		Beep()
+		return
+	}
+	pos := e.pos
+	e.replace(pos, pos, text)
+	e.move(pos + len(text))
+}
+
+// kill removes buf[start:end] and puts it on the clipboard
+func (e *LineEditor) kill(start int, end int) {
+	if start >= end {
+		return
+	}
+	if !e.Secret {
+		clipboard = string(e.buf[start:end])
+	}
+	e.replace(start, end, nil)
+}
+
+// recall replaces the line with the history entry at index.  The index after the newest entry is the line being entered.
+func (e *LineEditor) recall(index int) {
+	if e.History == nil || e.Secret || index < 0 || index > len(e.History.entries) {
+		Beep()
+		return
+	}
+	if e.index == len(e.History.entries) {
+		e.saved = string(e.buf)
+	}
+	e.index = index
+	if index == len(e.History.entries) {
+		e.buf = []rune(e.saved)
+	} else {
+		e.buf = []rune(e.History.entries[index])
+	}
+	e.move(len(e.buf))
+}
+
+// searchKey handles a key during a history search.  It returns ActionNone if the key was used by the search,
+// otherwise the search ends with the match in the line and the returned action is performed.
+func (e *LineEditor) searchKey(ch KeyEvent, action Action) Action {
+	switch action {
+	case ActionHistorySearch:
+		e.searchAt = e.History.search(string(e.query), e.searchAt)
+	case ActionBackspace:
+		if len(e.query) > 0 {
+			e.query = e.query[:len(e.query)-1]
+		}
+		e.searchAt = e.History.search(string(e.query), len(e.History.entries))
+	case ActionCancel:
+		e.buf = []rune(e.saved)
+		e.pos = len(e.buf)
+		e.endSearch()
+		return ActionNone
+	case ActionNone:
+		if !isPrintable(ch) {
+			Beep()
+			return ActionNone
+		}
+		e.query = append(e.query, keyRune(ch))
+		e.searchAt = e.History.search(string(e.query), min(e.searchAt+1, len(e.History.entries)))
+	default:
+		e.index = len(e.History.entries)
+		e.endSearch()
+		if action == ActionAccept {
+			return ActionNone
+		}
+		return action
+	}
+	if e.searchAt < 0 {
+		Beep()
+		e.searchAt = len(e.History.entries)
+		e.buf = e.buf[:0]
+	} else {
+		e.buf = []rune(e.History.entries[e.searchAt])
+	}
+	e.pos = len(e.buf)
+	e.prompt = searchPrompt(e.query)
+	e.draw()
+	return ActionNone
+}
+
+// endSearch ends a history search, removing its prompt
+func (e *LineEditor) endSearch() {
+	e.search = false
+	e.query = nil
+	e.prompt = ""
+	e.draw()
+}
+
+// searchPrompt returns the prompt shown during a history search for query
+func searchPrompt(query []rune) string {
+	return "(search)`" + string(query) + "': "
+}
+
+// complete completes the word left of the cursor.  A single match replaces the word, several matches
+// insert their common prefix and are listed below the line.
+func (e *LineEditor) complete() {
+	if e.Complete == nil || e.Secret {
+		Beep()
+		return
+	}
+	start := e.pos
+	for start > 0 && e.buf[start-1] != ' ' {
+		start--
+	}
+	candidates := e.Complete(string(e.buf), e.pos)
+	if len(candidates) == 0 {
+		Beep()
+		return
+	}
+	prefix := []rune(candidates[0])
+	for _, c := range candidates[1:] {
+		r := []rune(c)
+		n := 0
+		for n < len(prefix) && n < len(r) && prefix[n] == r[n] {
+			n++
 		}
+		prefix = prefix[:n]
+	}
+	if len(candidates) == 1 {
+		prefix = append(prefix, ' ')
+	}
+	if len(prefix) > e.pos-start {
+		if e.Max > 0 && len(e.buf)-(e.pos-start)+len(prefix) > e.Max {
+			Beep()
+			return
+		}
+		e.replace(start, e.pos, prefix)
+		e.move(start + len(prefix))
+	}
+	if len(candidates) > 1 && e.row+1 < Rows() {
+		list := strings.Join(candidates, "  ")
+		list = string([]rune(list)[:min(len([]rune(list)), Cols()-1)])
+		Locate(e.row+1, 0)
+		Print(list)
+		e.below = len([]rune(list))
+	}
+}
+
+// clearCompletions erases the completion list shown below the line
+func (e *LineEditor) clearCompletions() {
+	if e.below > 0 {
+		Locate(e.row+1, 0)
+		Print(strings.Repeat(" ", e.below))
+		e.below = 0
+	}
+}
+
+// draw redraws the prompt and the visible part of the line, scrolling it to keep the cursor on screen
+func (e *LineEditor) draw() {
+	prompt := []rune(e.prompt)
+	width := Cols() - e.col - len(prompt) - 1
+	if e.Max > 0 && e.Max < width {
+		width = e.Max + 1
+	}
+	width = max(width, 1)
+	if e.pos < e.scroll {
+		e.scroll = e.pos
+	} else if e.pos >= e.scroll+width {
+		e.scroll = e.pos - width + 1
+	}
+	visible := string(prompt) + e.shown(min(e.scroll, len(e.buf)), min(e.scroll+width, len(e.buf)))
+	length := len([]rune(visible))
+	Locate(e.row, e.col)
+	Print(visible + strings.Repeat(" ", max(0, e.drawn-length)))
+	e.drawn = length
+}
+
+// shown returns how buf[start:end] is echoed, masking a secret line
+func (e *LineEditor) shown(start int, end int) string {
+	if !e.Secret {
+		return string(e.buf[start:end])
 	}
-	if ch.Key == 27 {
+	if e.Mask == 0 {
 		return ""
 	}
-	return entry
+	return strings.Repeat(string(e.Mask), end-start)
 }
@@ -0,0 +1,116 @@
+package cons
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var lineMode = !IsTerminal()
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// GetLineMode returns true if menus, forms and line input use plain numbered lists and one prompt per line
+// on stdin/stdout instead of the console.  It is on when the program starts without a console.
+func GetLineMode() bool {
+	return lineMode
+}
+
+// SetLineMode turns line mode on or off
+func SetLineMode(value bool) {
+	lineMode = value
+}
+
+// readLine reads a line from stdin without the line ending
+func readLine() (string, error) {
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && (line == "" || !errors.Is(err, io.EOF)) {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// chooseLines is Choose for line mode.  It returns 0 if stdin ends before a valid choice.
+func chooseLines(title string, subTitle string, items []string) int {
+	if len(title) > 0 {
+		fmt.Println(title)
+	}
+	if len(subTitle) > 0 {
+		fmt.Println(subTitle)
+	}
+	for i, item := range items {
+		fmt.Printf("%2d. %s\n", i+1, item)
+	}
+	for {
+		fmt.Printf("Choice? (1-%d) ", len(items))
+		input, err := readLine()
+		if err != nil {
+			fmt.Println()
+			return 0
+		}
+		c, err := strconv.Atoi(strings.TrimSpace(input))
+		if err == nil && c >= 1 && c <= len(items) {
+			return c
+		}
+		fmt.Printf("'%s' is not valid.\n", input)
+	}
+}
+
+// entryLines is StartEntry for line mode.  Each field is prompted for on its own line showing the current value,
+// which an empty line keeps and a single - clears.  \- enters a -.  It returns false if stdin ends before all
+// fields are entered.
+func entryLines(fields []InputField) (bool, error) {
+	for index := range fields {
+		field := &fields[index]
+		for {
+			if field.value != "" && !field.secret {
+				fmt.Printf("%s [%s]: ", field.prompt, field.value)
+			} else {
+				fmt.Printf("%s: ", field.prompt)
+			}
+			input, err := readLine()
+			if err != nil {
+				fmt.Println()
+				return false, nil
+			}
+			switch input {
+			case "":
+				input = field.value
+			case "-":
+				input = ""
+			case `\-`:
+				input = "-"
+			}
+			if msg := checkFieldText(field, input); msg != "" {
+				fmt.Println(msg)
+				continue
+			}
+			previous := field.value
+			field.value = input
+			if !isFieldValid(field) {
+				field.value = previous
+				fmt.Printf("'%s' is not valid.\n", input)
+				continue
+			}
+			break
+		}
+	}
+	trimFields(fields)
+	return true, nil
+}
+
+// checkFieldText checks that text fits the field and passes its key validator, returning a message if it does not
+func checkFieldText(field *InputField, text string) string {
+	if len([]rune(text)) > field.size {
+		return fmt.Sprintf("At most %d characters are allowed.", field.size)
+	}
+	for _, r := range text {
+		if key := RuneKey(r); !isPrintable(key) || !isKeyValid(field, key) {
+			return fmt.Sprintf("'%c' is not valid.", r)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,186 @@
+package cons
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+)
+
+// maxTag is the longest markup tag.  A [ not closed within it is written as text.
+const maxTag = 32
+
+// colorNames are the color names used in markup
+var colorNames = map[string]int8{
+	"black": ColorBlack, "blue": ColorBlue, "green": ColorGreen, "cyan": ColorCyan, "red": ColorRed,
+	"magenta": ColorMagenta, "yellow": ColorYellow, "white": ColorWhite, "gray": ColorGray, "grey": ColorGray,
+	"brightblue": ColorBrightBlue, "brightgreen": ColorBrightGreen, "brightcyan": ColorBrightCyan,
+	"brightred": ColorBrightRed, "brightmagenta": ColorBrightMagenta, "brightyellow": ColorBrightYellow,
+	"brightwhite": ColorBrightWhite,
+}
+
+// MarkupWriter is an io.Writer that draws text with inline color markup on the screen, i.e.
+// "[yellow:blue]Total:[-] 12.00".  [foreground], [foreground:background] and [:background] push a style,
+// using color names such as red or brightred.  [-] pops back to the style before the last push.
+// [[ writes a [ and brackets that are not markup, such as [1], are written as they are.  In line mode, or when
+// stdout is not a terminal, the markup is stripped and the text written to stdout.
+type MarkupWriter struct {
+	out     io.Writer
+	stack   [][2]int8
+	pending []byte
+}
+
+// NewMarkupWriter returns a writer that draws markup on the screen
+func NewMarkupWriter() *MarkupWriter {
+	return &MarkupWriter{}
+}
+
+// NewPlainMarkupWriter returns a writer that strips markup and writes the text to w
+func NewPlainMarkupWriter(w io.Writer) *MarkupWriter {
+	return &MarkupWriter{out: w}
+}
+
+// StripMarkup returns text without its color markup
+func StripMarkup(text string) string {
+	var sb strings.Builder
+	w := NewPlainMarkupWriter(&sb)
+	w.Write([]byte(text))
+	w.Flush()
+	return sb.String()
+}
+
+// plain returns the writer markup is stripped for, or nil when drawing on the screen
+func (w *MarkupWriter) plain() io.Writer {
+	if w.out != nil {
+		return w.out
+	}
+	if lineMode || isConsole() && !IsTerminal() {
+		return os.Stdout
+	}
+	return nil
+}
+
+// Write draws p, changing colors as markup tags are read.  A tag split across writes is held until it is complete.
+func (w *MarkupWriter) Write(p []byte) (int, error) {
+	data := append(w.pending, p...)
+	w.pending = nil
+	var text []byte
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '[')
+		if i < 0 {
+			text = append(text, data...)
+			break
+		}
+		text = append(text, data[:i]...)
+		data = data[i:]
+		if len(data) > 1 && data[1] == '[' {
+			text = append(text, '[')
+			data = data[2:]
+			continue
+		}
+		end := bytes.IndexByte(data, ']')
+		if end < 0 && len(data) < maxTag {
+			w.pending = append([]byte(nil), data...)
+			break
+		}
+		style, ok := parseMarkup(string(data[1:max(end, 1)]))
+		if end < 0 || !ok {
+			text = append(text, '[')
+			data = data[1:]
+			continue
+		}
+		if err := w.emit(text); err != nil {
+			return len(p), err
+		}
+		text = text[:0]
+		w.apply(style)
+		data = data[end+1:]
+	}
+	return len(p), w.emit(text)
+}
+
+// Flush writes a held partial tag as text and pops all styles, restoring the colors from before the first push
+func (w *MarkupWriter) Flush() error {
+	pending := w.pending
+	w.pending = nil
+	for len(w.stack) > 0 {
+		w.apply(nil)
+	}
+	return w.emit(pending)
+}
+
+// emit writes text to the screen or, with markup stripped, to the plain writer
+func (w *MarkupWriter) emit(text []byte) error {
+	if len(text) == 0 {
+		return nil
+	}
+	if out := w.plain(); out != nil {
+		_, err := out.Write(text)
+		return err
+	}
+	_, err := screen.Write(text)
+	return err
+}
+
+// apply pushes a style, or pops the last one if style is nil.  Colors are left alone, and nothing is pushed,
+// when markup is stripped or the terminal has none.
+func (w *MarkupWriter) apply(style []int8) {
+	drawing := w.plain() == nil && canDrawColor()
+	if style == nil {
+		if len(w.stack) == 0 {
+			return
+		}
+		prior := w.stack[len(w.stack)-1]
+		w.stack = w.stack[:len(w.stack)-1]
+		if drawing {
+			SetColor(prior[0], prior[1])
+		}
+		return
+	}
+	if !drawing {
+		return
+	}
+	foreground, background := GetColor()
+	w.stack = append(w.stack, [2]int8{foreground, background})
+	if style[0] >= 0 {
+		foreground = style[0]
+	}
+	if style[1] >= 0 {
+		background = style[1]
+	}
+	SetColor(foreground, background)
+}
+
+// parseMarkup returns the foreground and background of a tag, with -1 for a color left as is, or nil for [-].
+// ok is false if tag is not markup.
+func parseMarkup(tag string) (style []int8, ok bool) {
+	if tag == "-" {
+		return nil, true
+	}
+	parts := strings.Split(tag, ":")
+	if len(parts) > 2 {
+		return nil, false
+	}
+	style = []int8{-1, -1}
+	for i, part := range parts {
+		if part == "" || part == "-" {
+			continue
+		}
+		color, ok := parseColor(part)
+		if !ok {
+			return nil, false
+		}
+		style[i] = color
+	}
+	if style[0] < 0 && style[1] < 0 {
+		return nil, false
+	}
+	return style, true
+}
+
+// parseColor returns the color for a name
+func parseColor(name string) (int8, bool) {
+	name = strings.ToLower(strings.NewReplacer("-", "", "_", "", " ", "").Replace(name))
+	color, ok := colorNames[name]
+	return color, ok
+}
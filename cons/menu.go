@@ -1,6 +1,7 @@
 package cons
 
 import (
+	"errors"
 	"fmt"
 	"lib/dt"
 	"lib/str"
@@ -8,48 +9,45 @@ import (
 )
 
 // Choose draws the menu and prompts for input.  Title and subTitle are not drawn if empty.
+// In line mode the items are printed as a numbered list and 0 is returned if stdin ends without a valid choice.
+// When answers are set by SetAnswers or the CONS_ANSWERS environment variable the choice is taken from them
+// and a rejected answer ends the program with an error.  When SetIdleCancel cancels the menu 0 is returned.
 func Choose(title string, subTitle string, items []string, borderStyle int, foreground int8, background int8, inputForeground int8, inputBackground int8, errorForeground int8) int {
-	const (
-		topLeft            = 0
-		topRight           = 1
-		bottomLeft         = 2
-		bottomRight        = 3
-		topIntersection    = 4
-		bottomIntersection = 5
-		horizontalLine     = 6
-		verticalLine       = 7
-		leftIntersection   = 8
-		rightIntersection  = 9
-	)
+	c, err := ChooseErr(title, subTitle, items, borderStyle, foreground, background, inputForeground, inputBackground, errorForeground)
+	if err != nil && !errors.Is(err, ErrIdleTimeout) {
+		fail(err)
+	}
+	return c
+}
+
+// ChooseErr draws the menu and prompts for input like Choose.  It returns ErrInterrupted for Control+C
+// and an *AnswerError if the answer supplied for the menu is rejected.
+func ChooseErr(title string, subTitle string, items []string, borderStyle int, foreground int8, background int8, inputForeground int8, inputBackground int8, errorForeground int8) (int, error) {
+	if answers != nil {
+		return answers.choose(title, items)
+	}
+	if lineMode {
+		return chooseLines(title, subTitle, items), nil
+	}
+	defer HoldScreen()()
+	defer statusLegend(menuLegend(len(items)))()
 
 	maxLength := 0
 	for _, v := range items {
 		maxLength = max(maxLength, len([]rune(v)))
 	}
 
-	singleLine := []string{"┌", "┐", "└", "┘", "┬", "┴", "─", "│", "├", "┤"}
-	doubleLine := []string{"╔", "╗", "╚", "╝", "╦", "╩", "═", "║", "╠", "╣"}
-	noLine := []string{" ", " ", " ", " ", " ", " ", " ", " ", " ", " "}
-	var lineChar []string
-	switch borderStyle {
-	case LineStyleSingle:
-		lineChar = singleLine
-	case LineStyleDouble:
-		lineChar = doubleLine
-	case LineStyleNone:
-		lineChar = noLine
-	}
-
-	tl := lineChar[topLeft]
-	tr := lineChar[topRight]
-	hl := lineChar[horizontalLine]
-	li := lineChar[leftIntersection]
-	ri := lineChar[rightIntersection]
-	ti := lineChar[topIntersection]
-	bi := lineChar[bottomIntersection]
-	bl := lineChar[bottomLeft]
-	br := lineChar[bottomRight]
-	vl := lineChar[verticalLine]
+	box := GetBoxChars(borderStyle)
+	tl := box.TopLeft
+	tr := box.TopRight
+	hl := box.Horizontal
+	li := box.LeftJoin
+	ri := box.RightJoin
+	ti := box.TopJoin
+	bi := box.BottomJoin
+	bl := box.BottomLeft
+	br := box.BottomRight
+	vl := box.Vertical
 
 	var line string
 	SetWindowSize(24, 80)
@@ -62,7 +60,7 @@ func Choose(title string, subTitle string, items []string, borderStyle int, fore
 		Center(subTitle)
 	}
 	Center(dt.Dtols(dt.Today()))
-	fmt.Println()
+	Println()
 	var row, col, count int
 	prompt := fmt.Sprint("Choice? (1-", strconv.Itoa(len(items)), ")  ")
 	maxLength = max(maxLength, len([]rune(prompt))+1)
@@ -71,7 +69,7 @@ func Choose(title string, subTitle string, items []string, borderStyle int, fore
 	if len(items) > 7 {
 		half := (count + 1) / 2
 		// 2 column menu
-		halfLine := str.LeftPad("", maxLength+1, hl)
+		halfLine := str.LeftPad("", maxLength+5, hl)
 		line = fmt.Sprint(tl, hl, halfLine, ti, hl, halfLine, tr)
 		Center(line)
 		fmt1 := fmt.Sprint(vl, " %2d. %-", strconv.Itoa(maxLength), "s ", vl, " %2d. %-", strconv.Itoa(maxLength), "s ", vl)
@@ -92,13 +90,13 @@ func Choose(title string, subTitle string, items []string, borderStyle int, fore
 		line = fmt.Sprint(li, hl, halfLine, bi, hl, halfLine, ri)
 		Center(line)
 		row = Row()
-		col = 40 + (len(prompt)-2)/2
 		line = fmt.Sprint(vl, " ", str.LeftPad(prompt, maxLength*2+11, " "), " ", vl)
+		col = (80-len([]rune(line)))/2 + len(prompt) + 1
 		Center(line)
 		line = fmt.Sprint(bl, str.LeftPad("", maxLength*2+13, hl), br)
 		Center(line)
 	} else {
-		halfLine := str.LeftPad("", maxLength+1, hl)
+		halfLine := str.LeftPad("", maxLength+5, hl)
 		line = fmt.Sprint(tl, hl, halfLine, tr)
 		Center(line)
 		fmt1 := fmt.Sprint(vl, " %2d. %-", strconv.Itoa(maxLength), "s ", vl)
@@ -110,8 +108,8 @@ func Choose(title string, subTitle string, items []string, borderStyle int, fore
 		line = fmt.Sprint(li, hl, halfLine, ri)
 		Center(line)
 		row = Row()
-		col = 40 + (len([]rune(prompt))-2)/2
 		line = fmt.Sprint(vl, " ", str.LeftPad(prompt, maxLength+4, " "), " ", vl)
+		col = (80-len([]rune(line)))/2 + len(prompt) + 1
 		Center(line)
 		line = fmt.Sprint(bl, str.LeftPad("", maxLength+6, hl), br)
 		Center(line)
@@ -122,23 +120,28 @@ func Choose(title string, subTitle string, items []string, borderStyle int, fore
 	SetColor(inputForeground, inputBackground)
 	for c < 1 || c > count {
 		Locate(row, col)
-		fmt.Printf("  \b\b")
-		input := LineInputLen(2)
-		c, err := strconv.ParseInt(input, 10, 64)
+		Printf("  \b\b")
+		editor := LineEditor{Max: 2}
+		input, err := editor.Read()
 		if err != nil {
-			c = 0
+			return 0, err
 		}
+		n, err := strconv.ParseInt(input, 10, 64)
+		if err != nil {
+			n = 0
+		}
+		c = int(n)
 		if c < 1 || int(c) > count {
 			SetColor(errorForeground, background)
 			Locate(row+2, msgCol)
-			fmt.Printf("'%s' is not valid.  ", input)
+			Printf("'%s' is not valid.  ", input)
 			SetColor(inputForeground, inputBackground)
 		}
 	}
 
 	SetColor(foreground, background)
 	Locate(row+2, msgCol)
-	fmt.Printf("                  ")
+	Printf("                  ")
 	Locate(row+2, msgCol)
-	return c
+	return c, nil
 }
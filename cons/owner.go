@@ -0,0 +1,176 @@
+package cons
+
+import "sync"
+
+// The screen is drawn by one goroutine, the one running menus, forms and line input.  Updates posted by other
+// goroutines are queued and drawn by that goroutine between keystrokes, so they never interleave with its own
+// drawing.
+var (
+	screenMu    sync.Mutex
+	holds       int
+	postedQueue []func()
+	postedReady = make(chan struct{}, 1)
+)
+
+// HoldScreen makes the calling goroutine the owner of the screen until the returned function is called.
+// Updates already posted are drawn first, so a status bar shown before a menu is in place when it draws.
+// Updates posted meanwhile are drawn by GetKey and Inkey between keystrokes, and when the last hold is
+// released.  Holds may be nested.
+func HoldScreen() (release func()) {
+	RunPosted()
+	screenMu.Lock()
+	holds++
+	screenMu.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			screenMu.Lock()
+			holds--
+			last := holds == 0
+			screenMu.Unlock()
+			if last {
+				RunPosted()
+			}
+		})
+	}
+}
+
+// Post queues an update from any goroutine, i.e. a clock or progress message.  Updates are drawn by the
+// goroutine drawing the screen: between keystrokes while GetKey or Inkey waits, when it releases HoldScreen or
+// when it calls RunPosted, as a program showing progress without reading keys does.  The cursor position and
+// colors are restored after the update, so it does not disturb input being edited.  f must not call Post,
+// HoldScreen or RunPosted.
+func Post(f func()) {
+	screenMu.Lock()
+	postedQueue = append(postedQueue, f)
+	screenMu.Unlock()
+	select {
+	case postedReady <- struct{}{}:
+	default:
+	}
+}
+
+// RunPosted draws the updates queued by Post.  GetKey and Inkey call it while waiting for keys; a program
+// drawing the screen without reading keys calls it from the goroutine that draws.
+func RunPosted() {
+	screenMu.Lock()
+	defer screenMu.Unlock()
+	if len(postedQueue) > 0 {
+		queue := postedQueue
+		postedQueue = nil
+		drawPosted(queue)
+	}
+}
+
+// drawPosted runs updates, restoring the cursor and colors afterward
+func drawPosted(updates []func()) {
+	row, col, cursorErr := screen.Cursor()
+	fg, bg, colorErr := screen.Color()
+	for _, f := range updates {
+		f()
+	}
+	if colorErr == nil {
+		screen.SetColor(fg, bg)
+	}
+	if cursorErr == nil {
+		screen.Locate(row, col)
+	}
+}
+
+// keyResult is a key read by waitKey
+type keyResult struct {
+	key KeyEvent
+	err error
+}
+
+// pendingRead is a key still being read from a source for a wait that ended without one
+type pendingRead struct {
+	src    KeySource
+	result chan keyResult
+}
+
+// pendingReads are kept when a wait ends or the key source changes, and taken by the next wait on the same
+// source, so the key is not lost and a source is never read twice at once.  Sources are compared with ==.
+var pendingReads []pendingRead
+
+// pendingKey returns the read still waiting on src, or nil
+func pendingKey(src KeySource) chan keyResult {
+	for _, r := range pendingReads {
+		if r.src == src {
+			return r.result
+		}
+	}
+	return nil
+}
+
+// startRead reads a key from src on another goroutine, keeping the read until endRead
+func startRead(src KeySource) chan keyResult {
+	result := make(chan keyResult, 1)
+	go func() {
+		key, err := src.GetKey()
+		result <- keyResult{key, err}
+	}()
+	pendingReads = append(pendingReads, pendingRead{src, result})
+	return result
+}
+
+// endRead forgets a read once its key has been received
+func endRead(result chan keyResult) {
+	for i, r := range pendingReads {
+		if r.result == result {
+			pendingReads = append(pendingReads[:i], pendingReads[i+1:]...)
+			return
+		}
+	}
+}
+
+// pruneReads forgets reads of sources other than the key source that ended with an error, i.e. a replay that
+// ran out or a closed session.  Reads still waiting or holding a key are kept for when their source returns.
+func pruneReads() {
+	kept := pendingReads[:0]
+	for _, r := range pendingReads {
+		if r.src != keySource {
+			select {
+			case k := <-r.result:
+				if k.err != nil {
+					continue
+				}
+				r.result <- k
+			default:
+			}
+		}
+		kept = append(kept, r)
+	}
+	clear(pendingReads[len(kept):])
+	pendingReads = kept
+}
+
+// waitKey waits for a key from the key source, drawing posted updates while it waits and handling the idle
+// timeouts set by SetIdleTimeout, SetScreenSaver and SetIdleCancel
+func waitKey() (KeyEvent, error) {
+	RunPosted()
+	idle := startIdle()
+	defer idle.stop()
+	for {
+		pending := pendingKey(keySource)
+		if pending == nil {
+			pending = startRead(keySource)
+		}
+		select {
+		case r := <-pending:
+			endRead(pending)
+			if idle.wake() && r.err == nil {
+				continue
+			}
+			return r.key, r.err
+		case <-postedReady:
+			if !idle.saving() {
+				RunPosted()
+			}
+		case <-idle.expired():
+			if err := idle.fire(); err != nil {
+				return KeyEvent{}, err
+			}
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package cons
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Pager shows text in a bordered window.  Up, Down, PgUp and PgDn scroll through the lines, Home and End go to
+// the first and last line, Left and Right scroll wide lines, a half screen at a time with Control, / searches,
+// n and N find the next and prior match, and Esc or q closes it.
+type Pager struct {
+	// Title is drawn in the top border
+	Title string
+	// LineNumbers shows a gutter with line numbers
+	LineNumbers bool
+	// Border is the window line style
+	Border int
+	// Foreground and Background are the text colors
+	Foreground int8
+	Background int8
+	// HighlightForeground and HighlightBackground are the colors of search matches
+	HighlightForeground int8
+	HighlightBackground int8
+	lines               [][]rune
+	top                 int
+	left                int
+	pattern             []rune
+	message             string
+	window              *Window
+}
+
+// NewPager creates a pager showing lines
+func NewPager(title string, lines []string) *Pager {
+	p := &Pager{Title: title, Border: LineStyleSingle, Foreground: ColorWhite, Background: ColorBlue,
+		HighlightForeground: ColorBlack, HighlightBackground: ColorBrightYellow}
+	for _, line := range lines {
+		p.lines = append(p.lines, expandTabs(strings.TrimRight(line, "\r")))
+	}
+	return p
+}
+
+// NewTextPager creates a pager showing text split into lines
+func NewTextPager(title string, text string) *Pager {
+	return NewPager(title, strings.Split(strings.TrimSuffix(text, "\n"), "\n"))
+}
+
+// NewReaderPager creates a pager showing the lines read from r
+func NewReaderPager(title string, r io.Reader) (*Pager, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return NewPager(title, lines), scanner.Err()
+}
+
+// expandTabs replaces tabs with spaces to the next multiple of 8 columns
+func expandTabs(line string) []rune {
+	var out []rune
+	for _, c := range line {
+		if c == '\t' {
+			out = append(out, ' ')
+			for len(out)%8 != 0 {
+				out = append(out, ' ')
+			}
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// Show shows the pager in a window filling the screen until it is closed.  In line mode the lines are printed.
+// It returns ErrInterrupted for Control+C.
+func (p *Pager) Show() error {
+	if lineMode {
+		for _, line := range p.lines {
+			fmt.Println(string(line))
+		}
+		return nil
+	}
+	rows, cols, err := WindowSizeErr()
+	if err != nil {
+		return err
+	}
+	return p.ShowIn(NewWindow(0, 0, rows, cols, p.Border, p.Foreground, p.Background))
+}
+
+// ShowIn shows the pager in w until it is closed, restoring the screen under it afterward
+func (p *Pager) ShowIn(w *Window) error {
+	defer HoldScreen()()
+	w.Title = p.Title
+	p.window = w
+	w.Open()
+	defer w.Close()
+	for {
+		p.draw()
+		key, err := GetKeyErr()
+		if err != nil {
+			return err
+		}
+		_, _, rows, cols := p.textArea()
+		step := 1
+		if key.Modifier&KeyControl != 0 {
+			step = cols / 2
+		}
+		p.message = ""
+		switch {
+		case key.Key == KeyEscape || key.Key == 'q' || key.Key == 'Q':
+			return nil
+		case key == Ctrl('C'):
+			if interrupted() {
+				return ErrInterrupted
+			}
+		case key.Key == KeyUp:
+			p.top--
+		case key.Key == KeyDown || key.Key == KeyEnter:
+			p.top++
+		case key.Key == KeyPageUp:
+			p.top -= rows
+		case key.Key == KeyPageDown || key.Key == ' ':
+			p.top += rows
+		case key.Key == KeyHome:
+			p.top, p.left = 0, 0
+		case key.Key == KeyEnd:
+			p.top = len(p.lines)
+		case key.Key == KeyLeft:
+			p.left -= step
+		case key.Key == KeyRight:
+			p.left += step
+		case key.Key == '/':
+			if err := p.prompt(); err != nil {
+				return err
+			}
+			p.find(p.top, 1)
+		case key.Key == 'n':
+			p.find(p.top+1, 1)
+		case key.Key == 'N':
+			p.find(p.top-1, -1)
+		}
+		p.top = max(0, min(p.top, len(p.lines)-rows))
+		p.left = max(0, min(p.left, p.widest()-cols+1))
+	}
+}
+
+// widest returns the length of the longest line
+func (p *Pager) widest() int {
+	widest := 0
+	for _, line := range p.lines {
+		widest = max(widest, len(line))
+	}
+	return widest
+}
+
+// gutter returns the width of the line number gutter
+func (p *Pager) gutter() int {
+	if !p.LineNumbers {
+		return 0
+	}
+	return len(fmt.Sprint(len(p.lines))) + 1
+}
+
+// textArea returns the screen area the lines are shown in, excluding the gutter
+func (p *Pager) textArea() (row int, col int, rows int, cols int) {
+	row, col, rows, cols = p.window.Inner()
+	g := p.gutter()
+	return row, col + g, rows, max(1, cols-g)
+}
+
+// draw draws the visible lines and the status in the bottom border
+func (p *Pager) draw() {
+	row, col, rows, cols := p.textArea()
+	for r := 0; r < rows; r++ {
+		index := p.top + r
+		if g := p.gutter(); g > 0 {
+			SetColor(p.Foreground, p.Background)
+			Locate(row+r, col-g)
+			if index < len(p.lines) {
+				Printf("%*d ", g-1, index+1)
+			} else {
+				Print(strings.Repeat(" ", g))
+			}
+		}
+		Locate(row+r, col)
+		var line []rune
+		if index < len(p.lines) {
+			line = p.lines[index]
+		}
+		p.drawLine(line, cols)
+	}
+	p.drawStatus()
+}
+
+// drawLine draws the visible part of a line, highlighting search matches
+func (p *Pager) drawLine(line []rune, cols int) {
+	highlight := p.matches(line)
+	var sb strings.Builder
+	lit := false
+	flush := func() {
+		if sb.Len() == 0 {
+			return
+		}
+		if lit {
+			SetColor(p.HighlightForeground, p.HighlightBackground)
+		} else {
+			SetColor(p.Foreground, p.Background)
+		}
+		Print(sb.String())
+		sb.Reset()
+	}
+	for i := p.left; i < p.left+cols; i++ {
+		c := ' '
+		if i < len(line) {
+			c = line[i]
+		}
+		on := i < len(highlight) && highlight[i]
+		if on != lit {
+			flush()
+			lit = on
+		}
+		sb.WriteRune(c)
+	}
+	flush()
+}
+
+// matches marks the characters of line that match the search pattern
+func (p *Pager) matches(line []rune) []bool {
+	if len(p.pattern) == 0 {
+		return nil
+	}
+	marks := make([]bool, len(line))
+	for i := 0; i+len(p.pattern) <= len(line); i++ {
+		if matchAt(line, i, p.pattern) {
+			for j := range p.pattern {
+				marks[i+j] = true
+			}
+		}
+	}
+	return marks
+}
+
+// matchAt returns true if pattern matches line at i, ignoring case
+func matchAt(line []rune, i int, pattern []rune) bool {
+	for j, c := range pattern {
+		if unicode.ToLower(line[i+j]) != unicode.ToLower(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// drawStatus draws the line position and any message in the bottom border
+func (p *Pager) drawStatus() {
+	w := p.window
+	if w.Border == LineStyleNone || w.Cols < 12 {
+		return
+	}
+	box := GetBoxChars(w.Border)
+	_, _, rows, _ := p.textArea()
+	last := min(p.top+rows, len(p.lines))
+	status := fmt.Sprintf(" %d-%d/%d ", min(p.top+1, last), last, len(p.lines))
+	SetColor(w.Foreground, w.Background)
+	Locate(w.Row+w.Rows-1, w.Col)
+	Print(box.BottomLeft, strings.Repeat(box.Horizontal, w.Cols-2), box.BottomRight)
+	Locate(w.Row+w.Rows-1, w.Col+w.Cols-1-len(status)-1)
+	Print(status)
+	if p.message != "" {
+		Locate(w.Row+w.Rows-1, w.Col+2)
+		Print(fitText(" "+p.message+" ", min(len([]rune(p.message))+2, w.Cols-len(status)-5)))
+	}
+}
+
+// prompt reads a search pattern in the bottom border
+func (p *Pager) prompt() error {
+	w := p.window
+	SetColor(w.Foreground, w.Background)
+	Locate(w.Row+w.Rows-1, w.Col+1)
+	Print(strings.Repeat(" ", w.Cols-2))
+	Locate(w.Row+w.Rows-1, w.Col+1)
+	Print("/")
+	editor := LineEditor{Max: w.Cols - 4}
+	pattern, err := editor.Read()
+	w.Draw()
+	if err != nil {
+		return err
+	}
+	if pattern != "" {
+		p.pattern = []rune(pattern)
+	}
+	return nil
+}
+
+// find searches from line start in direction step for the pattern, wrapping around, and scrolls to the match
+func (p *Pager) find(start int, step int) {
+	if len(p.pattern) == 0 || len(p.lines) == 0 {
+		return
+	}
+	_, _, _, cols := p.textArea()
+	for n := 0; n < len(p.lines); n++ {
+		index := ((start+n*step)%len(p.lines) + len(p.lines)) % len(p.lines)
+		line := p.lines[index]
+		for i := 0; i+len(p.pattern) <= len(line); i++ {
+			if matchAt(line, i, p.pattern) {
+				p.top = index
+				if i < p.left || i+len(p.pattern) > p.left+cols {
+					p.left = max(0, i-cols/4)
+				}
+				return
+			}
+		}
+	}
+	p.message = "Not found: " + string(p.pattern)
+}
@@ -0,0 +1,311 @@
+package cons
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ProgressLogInterval is how often progress is logged as a line of text when not on a terminal
+var ProgressLogInterval = 5 * time.Second
+
+// meter is the placement and redrawing shared by Progress, Spinner and Gauge
+type meter struct {
+	label      string
+	text       func(width int) string
+	row        int
+	col        int
+	width      int
+	foreground int8
+	background int8
+	pending    int32
+	mu         sync.Mutex
+	logged     time.Time
+}
+
+// init places a meter on the cursor line, moving the cursor to the next line.  text returns what is
+// drawn for a width.
+func (m *meter) init(label string, text func(width int) string) {
+	m.label = label
+	m.text = text
+	if lineMode {
+		return
+	}
+	row, col, _ := CursorErr()
+	_, cols, _ := WindowSizeErr()
+	m.foreground, m.background, _ = GetColorErr()
+	m.row, m.col, m.width = row, col, cols-col-1
+	Println()
+}
+
+// Place moves the meter to row, col, makes it width columns wide and draws it
+func (m *meter) Place(row int, col int, width int) {
+	Post(func() {
+		m.row, m.col, m.width = row, col, width
+	})
+	m.redraw()
+}
+
+// PlaceIn moves the meter to a row inside a window, using the window's width and colors, and draws it
+func (m *meter) PlaceIn(w *Window, row int) {
+	top, left, _, cols := w.Inner()
+	Post(func() {
+		m.row, m.col, m.width = top+row, left+1, cols-2
+		m.foreground, m.background = w.Foreground, w.Background
+	})
+	m.redraw()
+}
+
+// redraw posts a redraw unless one is already waiting, so frequent updates from a worker do not queue up
+func (m *meter) redraw() {
+	if lineMode || !atomic.CompareAndSwapInt32(&m.pending, 0, 1) {
+		return
+	}
+	Post(func() {
+		atomic.StoreInt32(&m.pending, 0)
+		SetColor(m.foreground, m.background)
+		Locate(m.row, m.col)
+		Print(fitText(m.text(m.width), m.width))
+	})
+}
+
+// log prints a line of text when not on a terminal, at most once per ProgressLogInterval unless force is true
+func (m *meter) log(force bool, text func() string) {
+	if !lineMode {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !force && time.Since(m.logged) < ProgressLogInterval {
+		return
+	}
+	m.logged = time.Now()
+	fmt.Println(text())
+}
+
+// fitText pads or truncates text to width characters
+func fitText(text string, width int) string {
+	r := []rune(text)
+	if len(r) > width {
+		return string(r[:max(width, 0)])
+	}
+	return text + strings.Repeat(" ", width-len(r))
+}
+
+// bar returns a bar width characters long filled to fraction
+func bar(fraction float64, width int) string {
+	fraction = math.Max(0, math.Min(fraction, 1))
+	filled := int(fraction*float64(width) + 0.5)
+	if !canDrawUnicode() {
+		return strings.Repeat("#", filled) + strings.Repeat("-", width-filled)
+	}
+	return strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+}
+
+// formatDuration formats a duration as h:mm:ss or m:ss
+func formatDuration(d time.Duration) string {
+	s := int(d.Round(time.Second) / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// formatRate formats a count per second with a k or M suffix
+func formatRate(rate float64) string {
+	switch {
+	case rate >= 1e6:
+		return fmt.Sprintf("%.1fM/s", rate/1e6)
+	case rate >= 1e4:
+		return fmt.Sprintf("%.1fk/s", rate/1e3)
+	case rate >= 10:
+		return fmt.Sprintf("%.0f/s", rate)
+	}
+	return fmt.Sprintf("%.1f/s", rate)
+}
+
+// Progress is a progress bar showing the percent done, the throughput and the estimated time remaining.
+// It is drawn on the cursor line unless placed elsewhere, and may be updated from any goroutine; updates are
+// drawn with Post, so a program waiting for workers rather than keys calls RunPosted to show them.
+// When not on a terminal the progress is logged as a line of text every ProgressLogInterval.
+type Progress struct {
+	meter
+	total int64
+	done  int64
+	start time.Time
+}
+
+// NewProgress creates a progress bar for total units of work on the cursor line and moves the cursor
+// below it.  The bar is drawn by the first update or when it is placed.
+func NewProgress(label string, total int64) *Progress {
+	p := &Progress{total: total, start: time.Now()}
+	p.init(label, p.progressText)
+	p.log(true, func() string { return p.label + " " + p.status() })
+	return p
+}
+
+// Add adds n units of work done
+func (p *Progress) Add(n int64) {
+	atomic.AddInt64(&p.done, n)
+	p.update(false)
+}
+
+// Set sets the units of work done
+func (p *Progress) Set(done int64) {
+	atomic.StoreInt64(&p.done, done)
+	p.update(false)
+}
+
+// Done marks all the work done
+func (p *Progress) Done() {
+	atomic.StoreInt64(&p.done, p.total)
+	p.update(true)
+}
+
+// update redraws or logs the progress
+func (p *Progress) update(force bool) {
+	p.redraw()
+	p.log(force, func() string { return p.label + " " + p.status() })
+}
+
+// fraction returns the fraction of the work done
+func (p *Progress) fraction() float64 {
+	if p.total <= 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&p.done)) / float64(p.total)
+}
+
+// status returns the percent done, throughput and estimated time remaining
+func (p *Progress) status() string {
+	done := atomic.LoadInt64(&p.done)
+	elapsed := time.Since(p.start)
+	status := fmt.Sprintf("%3.0f%%", p.fraction()*100)
+	if done > 0 && elapsed > 0 {
+		rate := float64(done) / elapsed.Seconds()
+		status += " " + formatRate(rate)
+		if done < p.total {
+			status += " ETA " + formatDuration(time.Duration(float64(p.total-done)/rate*float64(time.Second)))
+		}
+	}
+	return status
+}
+
+// progressText returns the bar and status for a width
+func (p *Progress) progressText(width int) string {
+	status := " " + p.status()
+	label := p.label
+	if label != "" {
+		label += " "
+	}
+	// the bar is left out when the label and status fill the width; the text is truncated to fit when drawn
+	size := max(0, width-len([]rune(label))-len([]rune(status)))
+	return label + bar(p.fraction(), size) + status
+}
+
+// spinnerFrames are the frames of the spinner animation
+var spinnerFrames = []string{"|", "/", "-", "\\"}
+
+// Spinner shows that work of unknown length is in progress.  It is drawn on the cursor line unless
+// placed elsewhere.  When not on a terminal the label is logged when it starts and stops.
+type Spinner struct {
+	meter
+	frame   int32
+	message atomic.Value
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewSpinner creates a spinner on the cursor line and moves the cursor below it
+func NewSpinner(label string) *Spinner {
+	s := &Spinner{}
+	s.init(label, s.spinnerText)
+	return s
+}
+
+// Start starts the animation
+func (s *Spinner) Start() {
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	s.log(true, func() string { return s.label + "..." })
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			s.redraw()
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				atomic.AddInt32(&s.frame, 1)
+			}
+		}
+	}()
+}
+
+// Stop stops the animation and replaces the spinner with message
+func (s *Spinner) Stop(message string) {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+		s.stop = nil
+	}
+	s.message.Store(message)
+	atomic.StoreInt32(&s.pending, 0)
+	s.redraw()
+	s.log(true, func() string { return s.label + " " + message })
+}
+
+// spinnerText returns the label and the current frame, or the message once stopped
+func (s *Spinner) spinnerText(int) string {
+	if message, ok := s.message.Load().(string); ok {
+		return s.label + " " + message
+	}
+	return s.label + " " + spinnerFrames[int(atomic.LoadInt32(&s.frame))%len(spinnerFrames)]
+}
+
+// Gauge shows a value within a range as a labeled bar, i.e. memory or disk used.  It is drawn on the
+// cursor line unless placed elsewhere.  When not on a terminal the value is logged every ProgressLogInterval.
+type Gauge struct {
+	meter
+	low    float64
+	high   float64
+	format string
+	value  atomic.Value
+}
+
+// NewGauge creates a gauge for values from low to high on the cursor line and moves the cursor below it.
+// The value is shown using the fmt verb format, i.e. "%.0f MB".  The gauge is drawn by the first Set or
+// when it is placed.
+func NewGauge(label string, low float64, high float64, format string) *Gauge {
+	g := &Gauge{low: low, high: high, format: format}
+	g.value.Store(low)
+	g.init(label, g.gaugeText)
+	return g
+}
+
+// Set changes the value shown
+func (g *Gauge) Set(value float64) {
+	g.value.Store(value)
+	g.redraw()
+	g.log(false, func() string { return g.label + " " + fmt.Sprintf(g.format, value) })
+}
+
+// gaugeText returns the label, bar and value for a width
+func (g *Gauge) gaugeText(width int) string {
+	value := g.value.Load().(float64)
+	shown := " " + fmt.Sprintf(g.format, value)
+	label := g.label
+	if label != "" {
+		label += " "
+	}
+	fraction := 0.0
+	if g.high > g.low {
+		fraction = (value - g.low) / (g.high - g.low)
+	}
+	return label + bar(fraction, max(0, width-len([]rune(label))-len([]rune(shown)))) + shown
+}
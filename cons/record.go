@@ -0,0 +1,180 @@
+package cons
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// recordHeader starts every key recording
+const recordHeader = "# cons keys: milliseconds since prior key, key, modifier, and the character of a KeyRune key"
+
+// recordedKey is a key event and the time since the key before it
+type recordedKey struct {
+	delay time.Duration
+	key   KeyEvent
+}
+
+// keyRecorder writes each key read by GetKey and Inkey with its timing
+type keyRecorder struct {
+	w      *bufio.Writer
+	closer io.Closer
+	last   time.Time
+	err    error
+}
+
+var recorder *keyRecorder
+
+// StartRecording records every key returned by GetKey and Inkey to a new file at path until StopRecording
+func StartRecording(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	RecordKeys(file)
+	recorder.closer = file
+	return nil
+}
+
+// RecordKeys records every key returned by GetKey and Inkey to w until StopRecording
+func RecordKeys(w io.Writer) {
+	StopRecording()
+	recorder = &keyRecorder{w: bufio.NewWriter(w), last: time.Now()}
+	_, recorder.err = fmt.Fprintln(recorder.w, recordHeader)
+}
+
+// StopRecording stops recording keys, returning the first error writing the recording
+func StopRecording() error {
+	if recorder == nil {
+		return nil
+	}
+	r := recorder
+	recorder = nil
+	if err := r.w.Flush(); r.err == nil {
+		r.err = err
+	}
+	if r.closer != nil {
+		if err := r.closer.Close(); r.err == nil {
+			r.err = err
+		}
+	}
+	return r.err
+}
+
+// record writes a key and the time since the last one, flushing so a crash does not lose it
+func (r *keyRecorder) record(key KeyEvent) {
+	now := time.Now()
+	if r.err == nil {
+		_, r.err = fmt.Fprintf(r.w, "%d %d %d", now.Sub(r.last).Milliseconds(), key.Key, key.Modifier)
+		if key.Key == KeyRune {
+			_, r.err = fmt.Fprintf(r.w, " %d", key.Rune)
+		}
+		if r.err == nil {
+			_, r.err = fmt.Fprintln(r.w)
+		}
+	}
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+	r.last = now
+}
+
+// Replay is a KeySource that plays back a recording made by StartRecording or RecordKeys
+type Replay struct {
+	keys  []recordedKey
+	speed float64
+	next  int
+	due   time.Time
+}
+
+// LoadReplay reads a recording from a file.  speed 1 plays back with the original timing,
+// 2 twice as fast and so on.  speed 0 plays back without waiting.
+func LoadReplay(path string, speed float64) (*Replay, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	r, err := ReadReplay(file, speed)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return r, nil
+}
+
+// ReadReplay reads a recording.  See LoadReplay for speed.
+func ReadReplay(in io.Reader, speed float64) (*Replay, error) {
+	r := &Replay{speed: speed}
+	scanner := bufio.NewScanner(in)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var ms int64
+		var key uint8
+		var mod int8
+		var char rune
+		_, err := fmt.Sscan(line, &ms, &key, &mod)
+		if err == nil && key == KeyRune {
+			_, err = fmt.Sscan(line, &ms, &key, &mod, &char)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		r.keys = append(r.keys, recordedKey{delay: time.Duration(ms) * time.Millisecond, key: KeyEvent{Key: key, Modifier: mod, Rune: char}})
+	}
+	return r, scanner.Err()
+}
+
+// ReplayFile plays back the recording at path through GetKey and Inkey.  See LoadReplay for speed.
+func ReplayFile(path string, speed float64) error {
+	r, err := LoadReplay(path, speed)
+	if err != nil {
+		return err
+	}
+	SetKeySource(r)
+	return nil
+}
+
+// GetKey waits until the next key is due and returns it.  io.EOF is returned after the last key.
+func (r *Replay) GetKey() (KeyEvent, error) {
+	if r.next >= len(r.keys) {
+		return KeyEvent{}, io.EOF
+	}
+	time.Sleep(time.Until(r.nextDue()))
+	return r.pop(), nil
+}
+
+// Inkey returns the next key if it is due or a 0 key if not.  io.EOF is returned after the last key.
+func (r *Replay) Inkey() (KeyEvent, error) {
+	if r.next >= len(r.keys) {
+		return KeyEvent{}, io.EOF
+	}
+	if time.Now().Before(r.nextDue()) {
+		return KeyEvent{}, nil
+	}
+	return r.pop(), nil
+}
+
+// nextDue returns when the next key should be returned, timing from when the prior key was returned
+func (r *Replay) nextDue() time.Time {
+	if r.due.IsZero() {
+		r.due = time.Now()
+	}
+	if r.speed <= 0 {
+		return r.due
+	}
+	return r.due.Add(time.Duration(float64(r.keys[r.next].delay) / r.speed))
+}
+
+// pop returns the next key and starts timing the one after it
+func (r *Replay) pop() KeyEvent {
+	key := r.keys[r.next].key
+	r.next++
+	r.due = time.Now()
+	return key
+}
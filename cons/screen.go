@@ -0,0 +1,123 @@
+package cons
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Screen is a display that console output is drawn on.  The console is the default screen;
+// a VirtualScreen draws into memory.
+type Screen interface {
+	// Write writes text at the cursor in the current colors, advancing the cursor
+	io.Writer
+	// Size returns the number of rows and columns
+	Size() (rows int, cols int, err error)
+	// Resize changes the number of rows and columns
+	Resize(rows int, cols int) error
+	// Cursor returns the cursor row and column
+	Cursor() (row int, col int, err error)
+	// Locate moves the cursor
+	Locate(row int, col int) error
+	// Color returns the colors used for output
+	Color() (foreground int8, background int8, err error)
+	// SetColor changes the colors used for output
+	SetColor(foreground int8, background int8) error
+	// Clear fills the screen with blanks in the current colors and moves the cursor home
+	Clear() error
+	// Capture returns the characters and colors on the screen
+	Capture() (*Snapshot, error)
+}
+
+// consoleScreen draws on the console
+type consoleScreen struct{}
+
+func (consoleScreen) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+func (consoleScreen) Size() (int, int, error) {
+	return sysWindowSize()
+}
+
+func (consoleScreen) Resize(rows int, cols int) error {
+	return sysSetWindowAndBufferSize(rows, cols, rows, cols)
+}
+
+func (consoleScreen) Cursor() (int, int, error) {
+	return sysCursor()
+}
+
+func (consoleScreen) Locate(row int, col int) error {
+	return sysLocate(row, col)
+}
+
+func (consoleScreen) Color() (int8, int8, error) {
+	return sysGetColor()
+}
+
+func (consoleScreen) SetColor(foreground int8, background int8) error {
+	return sysSetColor(foreground, background)
+}
+
+func (consoleScreen) Clear() error {
+	return sysCls()
+}
+
+func (consoleScreen) Capture() (*Snapshot, error) {
+	return sysCapture()
+}
+
+var screen Screen = consoleScreen{}
+
+// GetScreen returns the screen that output is drawn on
+func GetScreen() Screen {
+	return screen
+}
+
+// SetScreen draws all console output on s, i.e. a VirtualScreen, and turns line mode off.
+// nil returns to the console.
+func SetScreen(s Screen) {
+	if s == nil {
+		screen = consoleScreen{}
+		lineMode = !IsTerminal()
+		return
+	}
+	screen = s
+	lineMode = false
+}
+
+// isConsole returns true if output is drawn on the console
+func isConsole() bool {
+	_, ok := screen.(consoleScreen)
+	return ok
+}
+
+// Output returns a writer that draws text on the screen at the cursor
+func Output() io.Writer {
+	return screen
+}
+
+// Print writes text on the screen at the cursor like fmt.Print
+func Print(a ...interface{}) {
+	fmt.Fprint(screen, a...)
+}
+
+// Println writes text on the screen at the cursor like fmt.Println
+func Println(a ...interface{}) {
+	fmt.Fprintln(screen, a...)
+}
+
+// Printf writes formatted text on the screen at the cursor like fmt.Printf
+func Printf(format string, a ...interface{}) {
+	fmt.Fprintf(screen, format, a...)
+}
+
+// CaptureScreen returns the characters and colors currently on the screen.  It waits for posted updates
+// being drawn to finish, so it may be called from any goroutine but not from an update.
+func CaptureScreen() (*Snapshot, error) {
+	screenMu.Lock()
+	defer screenMu.Unlock()
+	snap, err := screen.Capture()
+	return snap, opError("CaptureScreen", err)
+}
@@ -1,6 +1,7 @@
 package cons
 
 import (
+	"errors"
 	"fmt"
 	"lib/dt"
 	"lib/str"
@@ -24,6 +25,16 @@ type InputField struct {
 	value         string
 	validateKey   func(field *InputField, key KeyEvent) bool
 	validateField func(field *InputField) bool
+	original      string
+	undo          []fieldEdit
+	redo          []fieldEdit
+	selecting     bool
+	anchor        int
+	secret        bool
+	mask          rune
+	popup         func(field *InputField) (string, bool, error)
+	hidden        int
+	scroll        int
 }
 
 // CreateInputField creates a fully populated input field
@@ -49,6 +60,20 @@ func ValidatedInputField(field *InputField, validateKey func(field *InputField,
 	field.validateField = validateField
 }
 
+// SecretInputField hides the field value, echoing mask for each character or nothing if mask is 0.
+// Secret fields have no undo and cannot be cut or copied.  The value is still held as a string, so unlike a
+// secret LineEditor it is not wiped from memory.
+func SecretInputField(field *InputField, mask rune) {
+	field.secret = true
+	field.mask = mask
+}
+
+// PopupInputField sets the popup opened by F4 or Alt+Down on the field, i.e. a date picker.  popup returns
+// the new field value and true if one was chosen, or an error such as ErrInterrupted to end entry.
+func PopupInputField(field *InputField, popup func(field *InputField) (string, bool, error)) {
+	field.popup = popup
+}
+
 // PositionInputField sets the row/col for the input field
 func PositionInputField(field *InputField, row int, col int) {
 	field.row = row
@@ -60,21 +85,37 @@ func FieldValue(field *InputField) string {
 	return field.value
 }
 
-// Entry simplifies full screen data entry.  Calculates row,col positions, draws screen, does input
+// Entry simplifies full screen data entry.  Calculates row,col positions, draws screen, does input.
+// In line mode each field is prompted for on its own line.  An empty line keeps the value and - clears it.
+// When answers are set by SetAnswers or the CONS_ANSWERS environment variable the field values are taken from them
+// and a rejected answer ends the program with an error.  When SetIdleCancel cancels entry false is returned.
 func Entry(title string, subTitle string, fields []InputField, foreground int8,
 	background int8, fieldForeground int8, fieldBackground int8, borderStyle int) bool {
-	const (
-		topLeft            = 0
-		topRight           = 1
-		bottomLeft         = 2
-		bottomRight        = 3
-		topIntersection    = 4
-		bottomIntersection = 5
-		horizontalLine     = 6
-		verticalLine       = 7
-		leftIntersection   = 8
-		rightIntersection  = 9
-	)
+	ok, err := EntryErr(title, subTitle, fields, foreground, background, fieldForeground, fieldBackground, borderStyle)
+	if err != nil && !errors.Is(err, ErrIdleTimeout) {
+		fail(err)
+	}
+	return ok
+}
+
+// EntryErr does full screen data entry like Entry.  It returns ErrInterrupted for Control+C
+// and an *AnswerError if an answer supplied for the form is rejected.
+func EntryErr(title string, subTitle string, fields []InputField, foreground int8,
+	background int8, fieldForeground int8, fieldBackground int8, borderStyle int) (bool, error) {
+	if answers != nil {
+		err := answers.entry(title, fields)
+		return err == nil, err
+	}
+	if lineMode {
+		if len(title) > 0 {
+			fmt.Println(title)
+		}
+		if len(subTitle) > 0 {
+			fmt.Println(subTitle)
+		}
+		return entryLines(fields)
+	}
+	defer HoldScreen()()
 
 	SetColor(foreground, background)
 	Cls()
@@ -85,7 +126,7 @@ func Entry(title string, subTitle string, fields []InputField, foreground int8,
 		Center(subTitle)
 	}
 	Center(dt.Dtols(dt.Today()))
-	fmt.Println()
+	Println()
 	promptLength := 0
 	valueLength := 0
 	for index := range fields {
@@ -96,28 +137,16 @@ func Entry(title string, subTitle string, fields []InputField, foreground int8,
 		fields[index].size = min(fields[index].size, Cols()-(promptLength+2))
 	}
 
-	singleLine := []string{"┌", "┐", "└", "┘", "┬", "┴", "─", "│", "├", "┤"}
-	doubleLine := []string{"╔", "╗", "╚", "╝", "╦", "╩", "═", "║", "╠", "╣"}
-	noLine := []string{" ", " ", " ", " ", " ", " ", " ", " ", " ", " "}
-	var lineChar []string
-	switch borderStyle {
-	case LineStyleSingle:
-		lineChar = singleLine
-	case LineStyleDouble:
-		lineChar = doubleLine
-	case LineStyleNone:
-		lineChar = noLine
-	}
-
-	tl := lineChar[topLeft]
-	tr := lineChar[topRight]
-	bl := lineChar[bottomLeft]
-	br := lineChar[bottomRight]
-	vl := lineChar[verticalLine]
+	box := GetBoxChars(borderStyle)
+	tl := box.TopLeft
+	tr := box.TopRight
+	bl := box.BottomLeft
+	br := box.BottomRight
+	vl := box.Vertical
 
 	width := 2 + promptLength + 2 + valueLength + 2
 	var line string
-	divider := strings.Repeat(lineChar[horizontalLine], width-2)
+	divider := strings.Repeat(box.Horizontal, width-2)
 
 	line = tl + divider + tr
 	Center(line)
@@ -136,9 +165,9 @@ func Entry(title string, subTitle string, fields []InputField, foreground int8,
 	bottom := Row()
 
 	PaintFields(fields, fieldForeground, fieldBackground)
-	ret := StartEntry(fields)
+	ret, err := StartEntryErr(fields, GetKeyMap())
 	Locate(bottom, 0)
-	return ret
+	return ret, err
 }
 
 func min(a, b int) int {
@@ -159,17 +188,19 @@ func PaintFields(fields []InputField, foreground int8, background int8) {
 	SetColor(foreground, background)
 	for i := range fields {
 		Locate(fields[i].row, fields[i].col)
-		fmt.Print(str.LeftPad(fields[i].value, fields[i].size, " "))
+		Print(str.LeftPad(shownValue(&fields[i]), fields[i].size, " "))
 	}
 }
 
 // StartEntry performs full screen entry.  It is assumed you have already updated the screen in preparation.
 // Params:
 // 	fields = The field locations, sizes, and values to capture input for.
+// Keys are interpreted using the key map set by SetKeyMap.  With DefaultKeyMap:
 // Enter, down arrow, and tab moves to the next field.  On the last field, Enter will exit while down arrow and tab will move to the first field.
 // up arrow and shift tab will move to the prior field.  When on the first field, they will move to the last field.
 // home moves to the begining of the line.
 // end moves to the end of the entered line.
+// control+home and control+end move to the first and last field.
 // left arrow moves left a character
 // right arrow moves right a character up to the end of line
 // control+left arrow moves left a word
@@ -178,167 +209,207 @@ func PaintFields(fields []InputField, foreground int8, background int8) {
 // delete deletes the current character
 // control+delete deletes to the end of line
 // backspace delets the character to the left of the cursor and moves left one character.
+// shift plus a movement key selects text.  typing, backspace or delete replaces the selection.
+// control+z undoes and control+y redoes changes to the current field.  control+r reverts it to its value when entry started.
+// control+x or shift+delete cuts, control+insert copies, and control+v or shift+insert pastes.  With no selection the whole field is cut or copied.
+// f4 or alt+down arrow opens the field's popup, i.e. the calendar of a date field.
 // f10 or control+Enter will exit entry with success.
-// escape will exit entry with failure.
+// In line mode each field is prompted for on its own line instead.  An empty line keeps the value and - clears it.
+// escape will exit entry with failure, as does waiting longer than the time set by SetIdleCancel.
+// control+c restores the console and ends the program unless the handler set by SetInterruptHandler continues entry.
 // typing a character will change the current character and advance the cursor.
 func StartEntry(fields []InputField) bool {
+	return StartEntryKeys(fields, GetKeyMap())
+}
+
+// StartEntryKeys performs full screen entry like StartEntry using keys as the key map for this form only.
+// Use GetKeyMap().With(overrides) to change a few bindings.
+func StartEntryKeys(fields []InputField, keys KeyMap) bool {
+	ok, err := StartEntryErr(fields, keys)
+	if err != nil && !errors.Is(err, ErrIdleTimeout) {
+		fail(err)
+	}
+	return ok
+}
+
+// StartEntryErr performs full screen entry like StartEntryKeys.  Control+C returns ErrInterrupted
+// unless the interrupt handler continues entry.  Supplied answers are keyed by the first field's prompt
+// and a rejected answer returns an *AnswerError.
+func StartEntryErr(fields []InputField, keys KeyMap) (bool, error) {
+	if answers != nil {
+		err := answers.entry("", fields)
+		return err == nil, err
+	}
+	if lineMode {
+		return entryLines(fields)
+	}
+	action, _, err := runEntry(fields, keys)
+	return action == ActionSave || action == ActionAccept, err
+}
+
+// runEntry runs full screen entry until a key bound to ActionAccept on the last field, ActionSave, ActionCancel,
+// ActionNextPage, ActionPrevPage or ActionExit ends it, returning the action and the key
+func runEntry(fields []InputField, keys KeyMap) (Action, KeyEvent, error) {
+	defer HoldScreen()()
+	defer statusLegend(formLegend(keys))()
 	currentField := 0
-	offset := 0
 	var ch KeyEvent
 	if len(fields) < 1 {
-		return false
+		return ActionCancel, ch, nil
+	}
+	for index := range fields {
+		fields[index].original = fields[index].value
+		fields[index].undo = nil
+		fields[index].redo = nil
+		fields[index].selecting = false
+	}
+	editor := fieldEditor{field: &fields[0]}
+	moveTo := func(index int) {
+		currentField = index
+		editor = fieldEditor{field: &fields[index], offset: len([]rune(fields[index].value))}
 	}
 
 	for {
-		field := &(fields[currentField])
-
-		Locate(field.row, field.col+offset)
-		ch = GetKey()
-		switch ch.Key {
-		case KeyEnter, KeyDown:
-			if !field.validateField(field) {
+		field := editor.field
+		editor.locate()
+		var err error
+		if ch, err = GetKeyErr(); err != nil {
+			return ActionCancel, ch, err
+		}
+		action := keys.Action(ch)
+		if edited, err := editor.key(action, ch); err != nil {
+			return action, ch, err
+		} else if edited {
+			continue
+		}
+		switch action {
+		case ActionAccept, ActionNextField:
+			if !isFieldValid(field) {
 				Beep()
 				break
 			}
-			currentField++
-			if currentField >= len(fields) {
-				if ch.Key == KeyEnter {
-					for index := range fields {
-						fields[index].value = strings.TrimRight(fields[index].value, " \t\r\n")
-					}
-					return true
+			clearSelection(field)
+			if currentField+1 >= len(fields) {
+				if action == ActionAccept {
+					trimFields(fields)
+					return action, ch, nil
 				}
-				currentField = 0
+				moveTo(0)
+				break
 			}
-			offset = len([]rune(fields[currentField].value))
-		case KeyTab:
-			if !field.validateField(field) {
+			moveTo(currentField + 1)
+		case ActionPrevField:
+			if !isFieldValid(field) {
 				Beep()
 				break
 			}
-			var dir int
-			if ch.Modifier&KeyShift != 0 {
-				dir = -1
-			} else {
-				dir = 1
-			}
-			currentField += dir
-			if currentField < 0 {
-				currentField = len(fields) - 1
-			} else if currentField >= len(fields) {
-				currentField = 0
-			}
-			offset = len([]rune(fields[currentField].value))
-		case KeyLeft:
-			if ch.Modifier&KeyControl != 0 {
-				for offset > 0 {
-					if field.value[offset-1] != ' ' {
-						break
-					}
-					offset--
-				}
-				for offset > 0 {
-					if field.value[offset-1] == ' ' {
-						break
-					}
-					offset--
-				}
-			} else {
-				if offset > 0 {
-					offset--
-				}
-			}
-		case KeyRight:
-			if ch.Modifier&KeyControl != 0 {
-				for offset < len([]rune(field.value)) {
-					if field.value[offset] == ' ' {
-						for field.value[offset] == ' ' {
-							offset++
-						}
-						break
-					}
-					offset++
-				}
-			} else {
-				if offset < len([]rune(field.value)) {
-					offset++
-				}
+			clearSelection(field)
+			if currentField == 0 {
+				moveTo(len(fields) - 1)
+				break
 			}
-			offset = min(offset, len([]rune(field.value)))
-		case KeyUp:
-			if !field.validateField(field) {
+			moveTo(currentField - 1)
+		case ActionFirstField:
+			if !isFieldValid(field) {
 				Beep()
 				break
 			}
-			currentField--
-			if currentField < 0 {
-				currentField = len(fields) - 1
-			}
-			offset = len([]rune(fields[currentField].value))
-		case KeyBackspace:
-			if offset > 0 {
-				offset--
-				field.value = field.value[:len([]rune(field.value))-1]
-				fmt.Println("\b \b") // backup
-			}
-		case KeyIns:
-			field.value = fmt.Sprint(field.value[:offset], " ", field.value[offset:])
-			Locate(field.row, field.col)
-			fmt.Println(field.value)
-			break
-		case KeyDel:
-			v := field.value
-			if ch.Modifier&KeyControl != 0 {
-				v = field.value[:offset]
-			}
-			field.value = fmt.Sprint(v[:offset], v[offset+1:])
-			Locate(field.row, field.col+offset)
-			if offset < len([]rune(v)) {
-				fmt.Print(field.value[offset:])
-			}
-			extra := 0
-			if offset > len([]rune(field.value)) {
-				extra = 1
-			}
-			fmt.Print(str.LeftPad("", field.size-len([]rune(field.value))-extra, " "))
-		case KeyHome:
-			if ch.Modifier&KeyControl != 0 {
-				if field.validateField(field) {
-					Beep()
-					break
-				}
-				currentField = 0
-				offset = len([]rune(fields[currentField].value))
-			} else {
-				offset = 0
+			clearSelection(field)
+			moveTo(0)
+		case ActionLastField:
+			if !isFieldValid(field) {
+				Beep()
+				break
 			}
-		case KeyEnd:
-			if ch.Modifier&KeyControl != 0 {
-				if !field.validateField(field) {
-					Beep()
-					break
-				}
-				currentField = len(fields) - 1
+			clearSelection(field)
+			last := &fields[len(fields)-1]
+			last.value = strings.TrimRight(last.value, " \t\r\n")
+			moveTo(len(fields) - 1)
+		case ActionSave:
+			trimFields(fields)
+			return action, ch, nil
+		case ActionCancel:
+			return action, ch, nil
+		case ActionNextPage, ActionPrevPage, ActionExit:
+			if !isFieldValid(field) {
+				Beep()
+				break
 			}
-			fields[currentField].value = strings.TrimRight(fields[currentField].value, " \t\r\n")
-			offset = len([]rune(fields[currentField].value))
-		case KeyF10, KeyControlEnter:
-			for index := range fields {
-				fields[index].value = strings.TrimRight(fields[index].value, " \t\r\n")
+			clearSelection(field)
+			trimFields(fields)
+			return action, ch, nil
+		case ActionInterrupt:
+			if interrupted() {
+				return action, ch, ErrInterrupted
 			}
-			return true
-		case KeyEscape:
-			return false
 		default:
-			if offset < field.size {
-				if field.validateKey(field, ch) {
-					fmt.Print(string(ch.Key))
-					field.value = fmt.Sprint(field.value[:offset], string(ch.Key), field.value[offset+1:])
-					offset++
-				} else {
-					Beep()
-				}
-			}
+			Beep()
+		}
+		if editor.field.selecting {
+			drawField(editor.field, editor.offset)
+		}
+	}
+}
+
+// editField replaces value[start:end] with text, saving the prior value for undo, and redraws the field.
+// It returns start as the new cursor offset.
+func editField(field *InputField, value []rune, start int, end int, text []rune) int {
+	pushUndo(field, start)
+	value = append(value[:start:start], append(text, value[end:]...)...)
+	if len(value) > field.size {
+		value = value[:field.size]
+	}
+	field.value = string(value)
+	field.selecting = false
+	drawField(field, start)
+	return start
+}
+
+// trimFields removes trailing white space from all field values
+func trimFields(fields []InputField) {
+	for index := range fields {
+		fields[index].value = strings.TrimRight(fields[index].value, " \t\r\n")
+	}
+}
+
+// isFieldValid runs the field validator if there is one
+func isFieldValid(field *InputField) bool {
+	return field.validateField == nil || field.validateField(field)
+}
+
+// isTextValid runs the key validator for each character of text
+func isTextValid(field *InputField, text []rune) bool {
+	for _, r := range text {
+		if key := RuneKey(r); !isPrintable(key) || !isKeyValid(field, key) {
+			return false
 		}
 	}
+	return true
+}
+
+// isKeyValid runs the key validator if there is one
+func isKeyValid(field *InputField, key KeyEvent) bool {
+	return field.validateKey == nil || field.validateKey(field, key)
+}
+
+// wordLeft returns the offset of the start of the word left of offset
+func wordLeft(value []rune, offset int) int {
+	for offset > 0 && value[offset-1] == ' ' {
+		offset--
+	}
+	for offset > 0 && value[offset-1] != ' ' {
+		offset--
+	}
+	return offset
+}
+
+// wordRight returns the offset of the start of the word right of offset
+func wordRight(value []rune, offset int) int {
+	for offset < len(value) && value[offset] != ' ' {
+		offset++
+	}
+	for offset < len(value) && value[offset] == ' ' {
+		offset++
+	}
+	return offset
 }
@@ -0,0 +1,112 @@
+package cons
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Cell is a character on the screen and its colors
+type Cell struct {
+	Char       rune
+	Foreground int8
+	Background int8
+}
+
+// Snapshot is a copy of the characters and colors on the screen
+type Snapshot struct {
+	// Rows is the number of rows
+	Rows int
+	// Cols is the number of columns
+	Cols int
+	// Cells holds Rows rows of Cols cells
+	Cells [][]Cell
+	// CursorRow and CursorCol are the cursor position
+	CursorRow int
+	CursorCol int
+}
+
+// htmlColors is the classic console palette as CSS colors, indexed by cons color
+var htmlColors = []string{
+	"#000000", "#000080", "#008000", "#008080", "#800000", "#800080", "#808000", "#c0c0c0",
+	"#808080", "#0000ff", "#00ff00", "#00ffff", "#ff0000", "#ff00ff", "#ffff00", "#ffffff",
+}
+
+// ansiColor converts a cons color, which has blue in bit 0 and red in bit 2, to an ANSI color number 0-7
+func ansiColor(color int8) int {
+	c := int(color & 7)
+	return (c&1)<<2 | c&2 | (c&4)>>2
+}
+
+// ansiSGR returns the escape sequence selecting a foreground and background color
+func ansiSGR(foreground int8, background int8) string {
+	fg := 30 + ansiColor(foreground)
+	if foreground&ColorBright != 0 {
+		fg += 60
+	}
+	bg := 40 + ansiColor(background)
+	if background&ColorBright != 0 {
+		bg += 60
+	}
+	return fmt.Sprintf("\x1b[%d;%dm", fg, bg)
+}
+
+// Line returns the text of a row with trailing blanks removed
+func (s *Snapshot) Line(row int) string {
+	var sb strings.Builder
+	for _, cell := range s.Cells[row] {
+		sb.WriteRune(cell.Char)
+	}
+	return strings.TrimRight(sb.String(), " ")
+}
+
+// Text returns the screen as plain text, one line per row with trailing blanks removed
+func (s *Snapshot) Text() string {
+	var sb strings.Builder
+	for row := range s.Cells {
+		sb.WriteString(s.Line(row))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// ANSI returns the screen as text with ANSI escape sequences for the colors
+func (s *Snapshot) ANSI() string {
+	var sb strings.Builder
+	for _, cells := range s.Cells {
+		fg, bg := int8(-1), int8(-1)
+		for _, cell := range cells {
+			if cell.Foreground != fg || cell.Background != bg {
+				fg, bg = cell.Foreground, cell.Background
+				sb.WriteString(ansiSGR(fg, bg))
+			}
+			sb.WriteRune(cell.Char)
+		}
+		sb.WriteString("\x1b[0m\n")
+	}
+	return sb.String()
+}
+
+// HTML returns the screen as a standalone HTML page titled title
+func (s *Snapshot) HTML(title string) string {
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>")
+	sb.WriteString(html.EscapeString(title))
+	sb.WriteString("</title>\n<style>\npre { font-family: Consolas, \"DejaVu Sans Mono\", monospace; line-height: 1.2; background: #000000; display: inline-block; padding: 4px; }\n</style>\n</head>\n<body>\n<pre>")
+	for _, cells := range s.Cells {
+		for start := 0; start < len(cells); {
+			end := start
+			var run strings.Builder
+			for end < len(cells) && cells[end].Foreground == cells[start].Foreground && cells[end].Background == cells[start].Background {
+				run.WriteRune(cells[end].Char)
+				end++
+			}
+			fmt.Fprintf(&sb, "<span style=\"color:%s;background:%s\">%s</span>",
+				htmlColors[cells[start].Foreground&15], htmlColors[cells[start].Background&15], html.EscapeString(run.String()))
+			start = end
+		}
+		sb.WriteByte('\n')
+	}
+	sb.WriteString("</pre>\n</body>\n</html>\n")
+	return sb.String()
+}
@@ -0,0 +1,222 @@
+package cons
+
+import (
+	"fmt"
+	"lib/str"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// StatusBar is a line on the last screen row showing a function key legend, record information and a clock.
+// While a status bar is shown Rows excludes its row, Cls redraws it, and menus and forms add their keys to
+// the legend.  Its methods may be called from any goroutine; the bar is changed and redrawn with Post, so
+// changes appear when the goroutine drawing the screen next waits for a key or calls RunPosted.
+type StatusBar struct {
+	foreground  int8
+	background  int8
+	keys        []legendKey
+	legend      string
+	info        string
+	clockLayout string
+	stop        chan struct{}
+}
+
+// legendKey is a key and the label shown for it in the legend
+type legendKey struct {
+	key   KeyEvent
+	label string
+}
+
+// statusBar is the status bar being shown.  It is set by updates posted from any goroutine and read by the
+// goroutine drawing, so it is atomic.
+var statusBar atomic.Pointer[StatusBar]
+
+// NewStatusBar creates a status bar drawn in the colors given, with a clock showing hours, minutes and seconds
+func NewStatusBar(foreground int8, background int8) *StatusBar {
+	return &StatusBar{foreground: foreground, background: background, clockLayout: "15:04:05"}
+}
+
+// GetStatusBar returns the status bar being shown, or nil
+func GetStatusBar() *StatusBar {
+	return statusBar.Load()
+}
+
+// AddKey adds a key to the start of the legend, i.e. AddKey(Key(KeyF1, 0), "Help") shows "F1 Help"
+func (s *StatusBar) AddKey(key KeyEvent, label string) *StatusBar {
+	Post(func() {
+		s.keys = append(s.keys, legendKey{key, label})
+		s.redraw()
+	})
+	return s
+}
+
+// SetInfo sets the text shown left of the clock, i.e. "Record 3 of 12"
+func (s *StatusBar) SetInfo(text string) {
+	Post(func() {
+		s.info = text
+		s.redraw()
+	})
+}
+
+// SetClock sets the time.Format layout of the clock.  An empty layout hides the clock.
+func (s *StatusBar) SetClock(layout string) {
+	Post(func() {
+		s.clockLayout = layout
+		s.redraw()
+	})
+}
+
+// Show draws the status bar on the last screen row, replacing any other status bar, and starts its clock
+func (s *StatusBar) Show() {
+	Post(func() {
+		prior := statusBar.Load()
+		if prior == s {
+			return
+		}
+		if prior != nil {
+			prior.hide()
+		}
+		statusBar.Store(s)
+		s.stop = make(chan struct{})
+		go s.tick(s.stop)
+		s.draw()
+	})
+}
+
+// Hide blanks the status bar row and stops the clock
+func (s *StatusBar) Hide() {
+	Post(func() {
+		if statusBar.Load() == s {
+			s.hide()
+		}
+	})
+}
+
+// hide stops the clock and blanks the status bar row
+func (s *StatusBar) hide() {
+	close(s.stop)
+	statusBar.Store(nil)
+	rows, cols, err := screen.Size()
+	if err == nil {
+		screen.SetColor(ColorWhite, ColorBlack)
+		screen.Locate(rows-1, 0)
+		fmt.Fprint(screen, strings.Repeat(" ", cols-1))
+	}
+}
+
+// tick redraws the clock each second until stop is closed
+func (s *StatusBar) tick(stop chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			Post(func() {
+				if s.clockLayout != "" {
+					s.redraw()
+				}
+			})
+		}
+	}
+}
+
+// setLegend replaces the legend generated for the active menu or form, returning a function restoring the prior one
+func (s *StatusBar) setLegend(legend string) (restore func()) {
+	prior := s.legend
+	s.legend = legend
+	s.redraw()
+	return func() {
+		s.legend = prior
+		s.redraw()
+	}
+}
+
+// redraw draws the status bar if it is being shown
+func (s *StatusBar) redraw() {
+	if statusBar.Load() == s {
+		s.draw()
+	}
+}
+
+// text returns the status bar text for a screen cols wide
+func (s *StatusBar) text(cols int) string {
+	var parts []string
+	for _, k := range s.keys {
+		parts = append(parts, KeyName(k.key)+" "+k.label)
+	}
+	if s.legend != "" {
+		parts = append(parts, s.legend)
+	}
+	left := " " + strings.Join(parts, "  ")
+	right := s.info
+	if s.clockLayout != "" {
+		if right != "" {
+			right += "  "
+		}
+		right += time.Now().Format(s.clockLayout)
+	}
+	right += " "
+	// the last column is left empty so the console does not scroll
+	width := cols - 1
+	if r := []rune(right); len(r) > width {
+		right = string(r[len(r)-width:])
+	}
+	return str.LeftPad(left, width-len([]rune(right)), " ") + right
+}
+
+// draw draws the status bar on the last screen row, restoring the cursor and colors
+func (s *StatusBar) draw() {
+	rows, cols, err := screen.Size()
+	if err != nil || cols < 2 {
+		return
+	}
+	row, col, cursorErr := screen.Cursor()
+	fg, bg, colorErr := screen.Color()
+	screen.SetColor(s.foreground, s.background)
+	screen.Locate(rows-1, 0)
+	fmt.Fprint(screen, s.text(cols))
+	if colorErr == nil {
+		screen.SetColor(fg, bg)
+	}
+	if cursorErr == nil {
+		screen.Locate(row, col)
+	}
+}
+
+// statusLegend shows legend in the status bar, if one is shown, returning a function restoring the prior legend
+func statusLegend(legend string) (restore func()) {
+	bar := statusBar.Load()
+	if bar == nil {
+		return func() {}
+	}
+	return bar.setLegend(legend)
+}
+
+// formActions are the actions shown in the legend for forms and their labels
+var formActions = []struct {
+	action Action
+	label  string
+}{{ActionSave, "Save"}, {ActionCancel, "Cancel"}}
+
+// formLegend returns the legend for a form using keys
+func formLegend(keys KeyMap) string {
+	var parts []string
+	for _, a := range formActions {
+		if key, ok := keys.KeyFor(a.action); ok {
+			parts = append(parts, KeyName(key)+" "+a.label)
+		}
+	}
+	return strings.Join(parts, "  ")
+}
+
+// menuLegend returns the legend for a menu of count items
+func menuLegend(count int) string {
+	legend := fmt.Sprintf("1-%d Choose", count)
+	if key, ok := lineKeyMap.KeyFor(ActionAccept); ok {
+		legend += "  " + KeyName(key) + " Select"
+	}
+	return legend
+}
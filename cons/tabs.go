@@ -0,0 +1,221 @@
+package cons
+
+import (
+	"fmt"
+	"lib/str"
+	"strings"
+	"unicode"
+)
+
+// Tab is one page of a TabSet holding a form.  An & in the title marks the letter that selects the tab with Alt,
+// otherwise the first letter is used.
+type Tab struct {
+	Title  string
+	Fields []InputField
+}
+
+// NewTab creates a tab showing fields
+func NewTab(title string, fields []InputField) *Tab {
+	return &Tab{Title: title, Fields: fields}
+}
+
+// label returns the title without the & marker
+func (t *Tab) label() string {
+	return strings.Replace(t.Title, "&", "", 1)
+}
+
+// hotKey returns the lower case letter that selects the tab with Alt
+func (t *Tab) hotKey() rune {
+	title := []rune(t.Title)
+	for i := 0; i+1 < len(title); i++ {
+		if title[i] == '&' {
+			return unicode.ToLower(title[i+1])
+		}
+	}
+	for _, c := range title {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			return unicode.ToLower(c)
+		}
+	}
+	return 0
+}
+
+// TabSet is a window of tabbed pages each holding a form.  Control+PgDn and Control+PgUp move to the next and
+// prior tab and Alt plus a tab's letter selects it.  Enter on the last field moves to the next tab, or saves on
+// the last tab.  The other keys are those of StartEntry.
+type TabSet struct {
+	// Title is drawn in the top border
+	Title string
+	Tabs  []*Tab
+	// Current is the index of the tab shown
+	Current int
+	// Border is the window line style
+	Border int
+	// Foreground and Background are the window colors
+	Foreground int8
+	Background int8
+	// FieldForeground and FieldBackground are the field colors
+	FieldForeground int8
+	FieldBackground int8
+	// TabForeground and TabBackground are the colors of the current tab's title
+	TabForeground int8
+	TabBackground int8
+	window        *Window
+}
+
+// NewTabSet creates a tab set showing tabs
+func NewTabSet(title string, tabs ...*Tab) *TabSet {
+	return &TabSet{Title: title, Tabs: tabs, Border: LineStyleSingle, Foreground: ColorWhite, Background: ColorBlue,
+		FieldForeground: ColorBlack, FieldBackground: ColorWhite, TabForeground: ColorBlack, TabBackground: ColorCyan}
+}
+
+// Run performs entry on the tabs in a window filling the screen.  It returns true if the fields were saved
+// and false if entry was canceled.  In line mode each tab's fields are prompted for in turn.
+func (t *TabSet) Run() (bool, error) {
+	if answers != nil {
+		for _, tab := range t.Tabs {
+			if err := answers.entry(tab.label(), tab.Fields); err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+	if lineMode {
+		for _, tab := range t.Tabs {
+			fmt.Println(tab.label())
+			if ok, err := entryLines(tab.Fields); !ok || err != nil {
+				return ok, err
+			}
+		}
+		return true, nil
+	}
+	rows, cols, err := WindowSizeErr()
+	if err != nil {
+		return false, err
+	}
+	return t.RunIn(NewWindow(0, 0, rows, cols, t.Border, t.Foreground, t.Background))
+}
+
+// RunIn performs entry on the tabs in w, restoring the screen under it afterward
+func (t *TabSet) RunIn(w *Window) (bool, error) {
+	if len(t.Tabs) == 0 {
+		return false, nil
+	}
+	defer HoldScreen()()
+	w.Title = t.Title
+	t.window = w
+	w.Open()
+	defer w.Close()
+	overrides := KeyMap{
+		Key(KeyPageDown, KeyControl): ActionNextPage,
+		Key(KeyPageUp, KeyControl):   ActionPrevPage,
+	}
+	for _, tab := range t.Tabs {
+		if c := tab.hotKey(); c != 0 && c < 0x80 {
+			overrides[Alt(byte(c))] = ActionExit
+			overrides[Alt(byte(unicode.ToUpper(c)))] = ActionExit
+		}
+	}
+	keys := GetKeyMap().With(overrides)
+	for {
+		t.Current = max(0, min(t.Current, len(t.Tabs)-1))
+		t.draw()
+		action, key, err := t.enter(keys)
+		if err != nil {
+			return false, err
+		}
+		switch action {
+		case ActionNextPage:
+			t.Current = (t.Current + 1) % len(t.Tabs)
+		case ActionPrevPage:
+			t.Current = (t.Current + len(t.Tabs) - 1) % len(t.Tabs)
+		case ActionExit:
+			for i, tab := range t.Tabs {
+				if tab.hotKey() == unicode.ToLower(keyRune(key)) {
+					t.Current = i
+				}
+			}
+		case ActionAccept:
+			if t.Current == len(t.Tabs)-1 {
+				return true, nil
+			}
+			t.Current++
+		case ActionSave:
+			return true, nil
+		case ActionCancel:
+			return false, nil
+		}
+	}
+}
+
+// enter performs entry on the current tab's fields.  A tab without fields waits for a key.
+func (t *TabSet) enter(keys KeyMap) (Action, KeyEvent, error) {
+	fields := t.Tabs[t.Current].Fields
+	if len(fields) > 0 {
+		return runEntry(fields, keys)
+	}
+	for {
+		key, err := GetKeyErr()
+		if err != nil {
+			return ActionNone, key, err
+		}
+		switch action := keys.Action(key); action {
+		case ActionAccept, ActionSave, ActionCancel, ActionNextPage, ActionPrevPage, ActionExit:
+			return action, key, nil
+		case ActionInterrupt:
+			if interrupted() {
+				return action, key, ErrInterrupted
+			}
+		}
+	}
+}
+
+// draw draws the tab titles and lays out and paints the current tab's fields
+func (t *TabSet) draw() {
+	w := t.window
+	w.Draw()
+	row, col, _, cols := w.Inner()
+	lines := NewLines().Horizontal(row+1, col, cols, LineStyleSingle)
+	if w.Border != LineStyleNone {
+		_, vertical := styleWeights(w.Border)
+		lines.Horizontal(row+1, col-1, cols+2, LineStyleSingle)
+		for _, c := range []int{col - 1, col + cols} {
+			lines.add(row+1, c, 0, vertical, false)
+			lines.add(row+1, c, 2, vertical, false)
+		}
+	}
+	Locate(row, col)
+	used := 0
+	for i, tab := range t.Tabs {
+		title := " " + tab.label() + " "
+		if used+len([]rune(title))+1 > cols {
+			break
+		}
+		if i == t.Current {
+			SetColor(t.TabForeground, t.TabBackground)
+		} else {
+			SetColor(t.Foreground, t.Background)
+		}
+		Print(title)
+		SetColor(t.Foreground, t.Background)
+		Print(GetBoxChars(LineStyleSingle).Vertical)
+		used += len([]rune(title)) + 1
+		lines.add(row+1, col+used-1, 0, lineLight, false)
+	}
+	lines.Draw()
+
+	fields := t.Tabs[t.Current].Fields
+	promptLength := 0
+	for index := range fields {
+		promptLength = max(promptLength, len([]rune(fields[index].prompt)))
+	}
+	for index := range fields {
+		r := row + 3 + index
+		fields[index].row = r
+		fields[index].col = col + 2 + promptLength + 2
+		fields[index].size = max(1, min(fields[index].size, cols-(promptLength+4)))
+		Locate(r, col+2)
+		Print(str.LeftPad(fields[index].prompt+":", promptLength+1, "."))
+	}
+	PaintFields(fields, t.FieldForeground, t.FieldBackground)
+}
@@ -0,0 +1,549 @@
+package cons
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrSessionClosed is returned for key reads after a telnet client disconnects
+var ErrSessionClosed = errors.New("cons: telnet session closed")
+
+// Telnet commands and options
+const (
+	telnetSE   = 240
+	telnetSB   = 250
+	telnetWill = 251
+	telnetWont = 252
+	telnetDo   = 253
+	telnetDont = 254
+	telnetIAC  = 255
+
+	telnetEcho = 1
+	telnetSGA  = 3
+	telnetNAWS = 31
+)
+
+// escapeWait is how long to wait for the rest of an escape sequence before taking Esc as a key
+var escapeWait = 50 * time.Millisecond
+
+// sizeWait is how long a new session waits for the client to report its window size
+var sizeWait = 500 * time.Millisecond
+
+// flushDelay is how long output is held so drawing goes out in a few packets
+const flushDelay = 5 * time.Millisecond
+
+// TelnetSession is a Screen and KeySource for a terminal connected with Telnet.  The client is put in
+// character mode with the server echoing, so keys arrive as they are pressed, and the window size is read
+// with NAWS, following resizes.  Output is kept in a VirtualScreen as well as sent, so Capture works.
+type TelnetSession struct {
+	conn      net.Conn
+	mu        sync.Mutex
+	shadow    *VirtualScreen
+	out       *bufio.Writer
+	flushing  bool
+	writeErr  error
+	input     chan byte
+	closed    chan struct{}
+	pending   []byte
+	sized     chan struct{}
+	sizedOnce sync.Once
+	ours      map[byte]bool
+	theirs    map[byte]bool
+}
+
+// NewTelnetSession negotiates character mode and the window size with the client on conn and returns the
+// session.  The screen is cleared to white on black.
+func NewTelnetSession(conn net.Conn) *TelnetSession {
+	s := &TelnetSession{conn: conn, shadow: NewVirtualScreen(24, 80), out: bufio.NewWriter(conn),
+		input: make(chan byte, 256), closed: make(chan struct{}), sized: make(chan struct{}), ours: map[byte]bool{},
+		theirs: map[byte]bool{}}
+	s.mu.Lock()
+	s.ours[telnetEcho], s.ours[telnetSGA], s.theirs[telnetSGA], s.theirs[telnetNAWS] = true, true, true, true
+	s.command(telnetWill, telnetEcho)
+	s.command(telnetWill, telnetSGA)
+	s.command(telnetDo, telnetSGA)
+	s.command(telnetDo, telnetNAWS)
+	s.send("\x1b[0;37;40m\x1b[2J\x1b[H")
+	s.flushLocked()
+	s.mu.Unlock()
+	go s.read()
+	select {
+	case <-s.sized:
+	case <-time.After(sizeWait):
+	}
+	return s
+}
+
+// RemoteAddr returns the address of the client
+func (s *TelnetSession) RemoteAddr() net.Addr {
+	return s.conn.RemoteAddr()
+}
+
+// Close resets the client's colors and closes the connection
+func (s *TelnetSession) Close() error {
+	s.mu.Lock()
+	s.send("\x1b[0m\n")
+	s.flushLocked()
+	s.mu.Unlock()
+	return s.conn.Close()
+}
+
+// sessionTurn holds a value while a session is drawing on the screen
+var sessionTurn = make(chan struct{}, 1)
+
+var (
+	telnetErrorMu      sync.Mutex
+	telnetErrorHandler func(s *TelnetSession, err error)
+)
+
+// SetTelnetErrorHandler registers a function called with the error a session run by ServeTelnet ends with.
+// nil logs the error with the standard logger.  It may be called while ServeTelnet runs.
+func SetTelnetErrorHandler(handler func(s *TelnetSession, err error)) {
+	telnetErrorMu.Lock()
+	defer telnetErrorMu.Unlock()
+	telnetErrorHandler = handler
+}
+
+// reportTelnetError passes the error a session ended with to the handler set by SetTelnetErrorHandler
+func reportTelnetError(s *TelnetSession, err error) {
+	telnetErrorMu.Lock()
+	handler := telnetErrorHandler
+	telnetErrorMu.Unlock()
+	if handler != nil {
+		handler(s, err)
+	} else {
+		log.Printf("cons: telnet %s: %v", s.RemoteAddr(), err)
+	}
+}
+
+// Run makes the session the screen and key source while f runs, then puts back the prior ones.  Console
+// output goes to one screen at a time, so sessions run one after another; a session started while another
+// runs tells its client it is waiting, and returns ErrSessionClosed if the client disconnects before its turn.
+func (s *TelnetSession) Run(f func() error) error {
+	select {
+	case sessionTurn <- struct{}{}:
+	default:
+		s.Write([]byte("Waiting for another session to finish...\n"))
+		s.Flush()
+		select {
+		case sessionTurn <- struct{}{}:
+		case <-s.closed:
+			return ErrSessionClosed
+		}
+	}
+	defer func() { <-sessionTurn }()
+	priorScreen, priorKeys := screen, keySource
+	SetScreen(s)
+	SetKeySource(s)
+	defer func() {
+		SetKeySource(priorKeys)
+		if _, ok := priorScreen.(consoleScreen); ok {
+			SetScreen(nil)
+		} else {
+			SetScreen(priorScreen)
+		}
+	}()
+	err := f()
+	s.Flush()
+	return err
+}
+
+// ServeTelnet accepts connections on l and runs handler in a new session for each, closing the connection
+// when it returns.  The sessions share the package's screen and key source, so only one operator is served
+// at a time: the sessions run one after another with Run and the others wait for their turn.  To serve
+// several operators at once, run a process per connection, i.e. started by inetd with a session made from
+// net.FileConn(os.Stdin).  An error a session ends with goes to the handler set by SetTelnetErrorHandler.
+// It returns when Accept fails, i.e. because l was closed.
+func ServeTelnet(l net.Listener, handler func(s *TelnetSession) error) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			s := NewTelnetSession(conn)
+			defer s.Close()
+			err := s.Run(func() error {
+				return handler(s)
+			})
+			if err != nil {
+				reportTelnetError(s, err)
+			}
+		}()
+	}
+}
+
+// command sends a Telnet option command.  s.mu must be held.
+func (s *TelnetSession) command(verb byte, option byte) {
+	s.out.Write([]byte{telnetIAC, verb, option})
+}
+
+// send sends text to the client, ending lines with CR LF.  s.mu must be held.
+func (s *TelnetSession) send(text string) {
+	s.out.WriteString(strings.ReplaceAll(text, "\n", "\r\n"))
+	if !s.flushing {
+		s.flushing = true
+		time.AfterFunc(flushDelay, func() { s.Flush() })
+	}
+}
+
+// Flush sends held output to the client
+func (s *TelnetSession) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushLocked()
+}
+
+// flushLocked sends held output.  s.mu must be held.
+func (s *TelnetSession) flushLocked() error {
+	s.flushing = false
+	if err := s.out.Flush(); err != nil && s.writeErr == nil {
+		s.writeErr = err
+	}
+	return s.writeErr
+}
+
+// Write writes text at the cursor in the current colors, advancing the cursor
+func (s *TelnetSession) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shadow.Write(p)
+	s.send(string(p))
+	return len(p), s.writeErr
+}
+
+// Size returns the client's window size
+func (s *TelnetSession) Size() (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shadow.Size()
+}
+
+// Resize is not supported; the client sets its window size
+func (s *TelnetSession) Resize(rows int, cols int) error {
+	return errors.ErrUnsupported
+}
+
+// Cursor returns the cursor row and column
+func (s *TelnetSession) Cursor() (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shadow.Cursor()
+}
+
+// Locate moves the cursor
+func (s *TelnetSession) Locate(row int, col int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.shadow.Locate(row, col); err != nil {
+		return err
+	}
+	s.send(fmt.Sprintf("\x1b[%d;%dH", row+1, col+1))
+	return s.writeErr
+}
+
+// Color returns the colors used for output
+func (s *TelnetSession) Color() (int8, int8, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shadow.Color()
+}
+
+// SetColor changes the colors used for output
+func (s *TelnetSession) SetColor(foreground int8, background int8) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shadow.SetColor(foreground, background)
+	s.send(ansiSGR(foreground, background))
+	return s.writeErr
+}
+
+// Clear fills the screen with blanks in the current colors and moves the cursor home
+func (s *TelnetSession) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.shadow.Clear()
+	s.send(ansiSGR(s.shadow.fg, s.shadow.bg) + "\x1b[2J\x1b[H")
+	return s.writeErr
+}
+
+// Capture returns the characters and colors on the client's screen
+func (s *TelnetSession) Capture() (*Snapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shadow.Capture()
+}
+
+// read reads from the client, answering option negotiation and queuing the data for GetKey.  The input
+// and closed channels are closed when the connection fails or is closed.
+func (s *TelnetSession) read() {
+	defer close(s.input)
+	defer close(s.closed)
+	in := bufio.NewReader(s.conn)
+	lastCR := false
+	for {
+		b, err := in.ReadByte()
+		if err != nil {
+			return
+		}
+		if b == telnetIAC {
+			if b, err = in.ReadByte(); err != nil {
+				return
+			}
+			if b != telnetIAC {
+				if err := s.telnetCommand(in, b); err != nil {
+					return
+				}
+				continue
+			}
+		}
+		// Enter arrives as CR NUL or CR LF
+		if lastCR && (b == 0 || b == '\n') {
+			lastCR = false
+			continue
+		}
+		lastCR = b == '\r'
+		s.input <- b
+	}
+}
+
+// telnetCommand handles the command after an IAC
+func (s *TelnetSession) telnetCommand(in *bufio.Reader, verb byte) error {
+	switch verb {
+	case telnetWill, telnetWont, telnetDo, telnetDont:
+		option, err := in.ReadByte()
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.negotiate(verb, option)
+		s.flushLocked()
+		s.mu.Unlock()
+	case telnetSB:
+		var data []byte
+		for {
+			b, err := in.ReadByte()
+			if err != nil {
+				return err
+			}
+			if b == telnetIAC {
+				if b, err = in.ReadByte(); err != nil {
+					return err
+				}
+				if b == telnetSE {
+					break
+				}
+			}
+			data = append(data, b)
+		}
+		if len(data) == 5 && data[0] == telnetNAWS {
+			cols, rows := int(data[1])<<8|int(data[2]), int(data[3])<<8|int(data[4])
+			if rows > 0 && cols > 0 {
+				s.mu.Lock()
+				s.shadow.Resize(rows, cols)
+				s.mu.Unlock()
+				s.sizedOnce.Do(func() { close(s.sized) })
+			}
+		}
+	}
+	return nil
+}
+
+// negotiate answers an option command.  Echo and suppress go ahead are done by the server and NAWS and
+// suppress go ahead by the client; other options, such as line mode, are refused.  A command that would not
+// change an option is not answered, so negotiation does not loop.  s.mu must be held.
+func (s *TelnetSession) negotiate(verb byte, option byte) {
+	switch verb {
+	case telnetDo:
+		if option != telnetEcho && option != telnetSGA {
+			s.command(telnetWont, option)
+		} else if !s.ours[option] {
+			s.ours[option] = true
+			s.command(telnetWill, option)
+		}
+	case telnetDont:
+		if s.ours[option] {
+			s.ours[option] = false
+			s.command(telnetWont, option)
+		}
+	case telnetWill:
+		if option != telnetNAWS && option != telnetSGA {
+			s.command(telnetDont, option)
+		} else if !s.theirs[option] {
+			s.theirs[option] = true
+			s.command(telnetDo, option)
+		}
+	case telnetWont:
+		if s.theirs[option] {
+			s.theirs[option] = false
+			s.command(telnetDont, option)
+		}
+		if option == telnetNAWS {
+			s.sizedOnce.Do(func() { close(s.sized) })
+		}
+	}
+}
+
+// readByte returns the next byte from the client, waiting up to wait for it, or forever if wait is negative.
+// ok is false if none came in time.
+func (s *TelnetSession) readByte(wait time.Duration) (b byte, ok bool, err error) {
+	if len(s.pending) > 0 {
+		b, s.pending = s.pending[0], s.pending[1:]
+		return b, true, nil
+	}
+	var timeout <-chan time.Time
+	if wait >= 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case b, open := <-s.input:
+		if !open {
+			return 0, false, ErrSessionClosed
+		}
+		return b, true, nil
+	case <-timeout:
+		return 0, false, nil
+	}
+}
+
+// GetKey waits for a key from the client
+func (s *TelnetSession) GetKey() (KeyEvent, error) {
+	s.Flush()
+	for {
+		b, _, err := s.readByte(-1)
+		if err != nil {
+			return KeyEvent{}, err
+		}
+		if key, ok := s.decodeKey(b); ok {
+			return key, nil
+		}
+	}
+}
+
+// Inkey returns a key if the client has sent one or a 0 key if not
+func (s *TelnetSession) Inkey() (KeyEvent, error) {
+	s.Flush()
+	for {
+		b, ok, err := s.readByte(0)
+		if err != nil || !ok {
+			return KeyEvent{}, err
+		}
+		if key, ok := s.decodeKey(b); ok {
+			return key, nil
+		}
+	}
+}
+
+// decodeKey returns the key starting with b, reading the rest of an escape sequence or UTF-8 character.  ok
+// is false for input that is not a key, such as invalid UTF-8.
+func (s *TelnetSession) decodeKey(b byte) (KeyEvent, bool) {
+	switch {
+	case b == 127 || b == KeyBackspace:
+		return Key(KeyBackspace, 0), true
+	case b == KeyEnter || b == KeyTab:
+		return Key(b, 0), true
+	case b == KeyControlEnter:
+		return Key(KeyControlEnter, KeyControl), true
+	case b == KeyEscape:
+		return s.decodeEscape()
+	case b < ' ':
+		return Key(b, KeyControl), b != 0
+	case b < 127:
+		return Key(b, 0), true
+	}
+	// Characters outside ASCII arrive as UTF-8
+	char := []byte{b}
+	for n := b << 1; n&0x80 != 0 && len(char) < utf8.UTFMax; n <<= 1 {
+		c, ok, _ := s.readByte(escapeWait)
+		if !ok {
+			break
+		}
+		char = append(char, c)
+	}
+	r, size := utf8.DecodeRune(char)
+	if r == utf8.RuneError || size != len(char) {
+		return KeyEvent{}, false
+	}
+	return RuneKey(r), true
+}
+
+// csiKeys are the keys of escape sequences ending in ~, by their first parameter
+var csiKeys = map[int]uint8{
+	1: KeyHome, 2: KeyIns, 3: KeyDel, 4: KeyEnd, 5: KeyPageUp, 6: KeyPageDown, 7: KeyHome, 8: KeyEnd,
+	11: KeyF1, 12: KeyF2, 13: KeyF3, 14: KeyF4, 15: KeyF5, 17: KeyF6, 18: KeyF7, 19: KeyF8, 20: KeyF9, 21: KeyF10,
+	23: KeyF11, 24: KeyF12,
+}
+
+// finalKeys are the keys of escape sequences by their final character
+var finalKeys = map[byte]uint8{
+	'A': KeyUp, 'B': KeyDown, 'C': KeyRight, 'D': KeyLeft, 'H': KeyHome, 'F': KeyEnd,
+	'P': KeyF1, 'Q': KeyF2, 'R': KeyF3, 'S': KeyF4,
+}
+
+// decodeEscape reads the key sent as an escape sequence, i.e. ESC [ A for Up or ESC [ 1 ; 5 C for
+// Control+Right.  Esc alone is the Esc key and Esc before a character is Alt plus the character.
+func (s *TelnetSession) decodeEscape() (KeyEvent, bool) {
+	b, ok, _ := s.readByte(escapeWait)
+	switch {
+	case !ok:
+		return Key(KeyEscape, 0), true
+	case b == '[' || b == 'O':
+	case b > ' ' && b < 127:
+		return Alt(b), true
+	default:
+		s.pending = append(s.pending, b)
+		return Key(KeyEscape, 0), true
+	}
+	var params []byte
+	final := byte(0)
+	for len(params) < 16 {
+		c, ok, _ := s.readByte(escapeWait)
+		if !ok {
+			break
+		}
+		if c >= 0x40 && c <= 0x7e {
+			final = c
+			break
+		}
+		params = append(params, c)
+	}
+	fields := strings.Split(string(params), ";")
+	var mods int8
+	if len(fields) > 1 {
+		if m, err := strconv.Atoi(fields[1]); err == nil && m > 1 {
+			bits := m - 1
+			if bits&1 != 0 {
+				mods |= KeyShift
+			}
+			if bits&2 != 0 {
+				mods |= KeyAlt
+			}
+			if bits&4 != 0 {
+				mods |= KeyControl
+			}
+		}
+	}
+	switch final {
+	case '~':
+		if n, err := strconv.Atoi(fields[0]); err == nil && csiKeys[n] != 0 {
+			return Key(csiKeys[n], mods), true
+		}
+	case 'Z':
+		return Key(KeyTab, KeyShift), true
+	default:
+		if key, found := finalKeys[final]; found {
+			return Key(key, mods), true
+		}
+	}
+	return KeyEvent{}, false
+}
@@ -0,0 +1,254 @@
+package cons
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrBadTerminfo is returned for a terminfo entry that cannot be parsed
+var ErrBadTerminfo = errors.New("cons: invalid terminfo entry")
+
+// Terminfo is a compiled terminfo entry describing what a terminal can do and the sequences that do it.
+// Extended capabilities such as Tc or XM are included with the standard ones.
+type Terminfo struct {
+	// Names are the terminal's names, i.e. xterm-256color and its description
+	Names   []string
+	Bools   map[string]bool
+	Numbers map[string]int
+	Strings map[string]string
+}
+
+// terminfoDirs returns the directories searched for compiled terminfo entries, in order
+func terminfoDirs() []string {
+	var dirs []string
+	if dir := os.Getenv("TERMINFO"); dir != "" {
+		dirs = append(dirs, dir)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".terminfo"))
+	}
+	if list := os.Getenv("TERMINFO_DIRS"); list != "" {
+		for _, dir := range strings.Split(list, ":") {
+			if dir == "" {
+				dir = "/usr/share/terminfo"
+			}
+			dirs = append(dirs, dir)
+		}
+	}
+	return append(dirs, "/etc/terminfo", "/lib/terminfo", "/usr/share/terminfo", "/usr/lib/terminfo")
+}
+
+// LoadTerminfo reads the compiled terminfo entry for term from the terminfo database
+func LoadTerminfo(term string) (*Terminfo, error) {
+	if term == "" || strings.ContainsAny(term, `/\`) || strings.HasPrefix(term, ".") {
+		return nil, fmt.Errorf("cons: no terminfo entry for %q", term)
+	}
+	for _, dir := range terminfoDirs() {
+		// Entries are filed under their first letter, or its hex code on case insensitive file systems
+		for _, sub := range []string{term[:1], fmt.Sprintf("%02x", term[0])} {
+			data, err := os.ReadFile(filepath.Join(dir, sub, term))
+			if err == nil {
+				return ParseTerminfo(data)
+			}
+		}
+	}
+	return nil, fmt.Errorf("cons: no terminfo entry for %q", term)
+}
+
+// terminfoReader reads the little endian values of a compiled entry
+type terminfoReader struct {
+	data []byte
+	pos  int
+	wide bool
+	err  error
+}
+
+// bytes returns the next n bytes
+func (r *terminfoReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || r.pos+n > len(r.data) {
+		r.err = ErrBadTerminfo
+		return make([]byte, max(n, 0))
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// short returns the next 16 bit value
+func (r *terminfoReader) short() int {
+	return int(int16(binary.LittleEndian.Uint16(r.bytes(2))))
+}
+
+// number returns the next number, 32 bits wide in the extended number format
+func (r *terminfoReader) number() int {
+	if r.wide {
+		return int(int32(binary.LittleEndian.Uint32(r.bytes(4))))
+	}
+	return r.short()
+}
+
+// align skips a byte to an even offset
+func (r *terminfoReader) align() {
+	if r.pos%2 != 0 {
+		r.bytes(1)
+	}
+}
+
+// cString returns the NUL terminated string at offset in table
+func cString(table []byte, offset int) (string, bool) {
+	if offset < 0 || offset >= len(table) {
+		return "", false
+	}
+	end := offset
+	for end < len(table) && table[end] != 0 {
+		end++
+	}
+	return string(table[offset:end]), true
+}
+
+// ParseTerminfo parses a compiled terminfo entry in the legacy or the extended number format
+func ParseTerminfo(data []byte) (*Terminfo, error) {
+	r := &terminfoReader{data: data}
+	switch r.short() {
+	case 0o432:
+	case 0o1036:
+		r.wide = true
+	default:
+		return nil, ErrBadTerminfo
+	}
+	nameSize, boolCount, numCount, strCount, tableSize := r.short(), r.short(), r.short(), r.short(), r.short()
+	ti := &Terminfo{Bools: map[string]bool{}, Numbers: map[string]int{}, Strings: map[string]string{}}
+	ti.Names = strings.Split(strings.TrimRight(string(r.bytes(nameSize)), "\x00"), "|")
+	for i, b := range r.bytes(boolCount) {
+		if b == 1 && i < len(terminfoBools) {
+			ti.Bools[terminfoBools[i]] = true
+		}
+	}
+	r.align()
+	for i := 0; i < numCount; i++ {
+		if n := r.number(); n >= 0 && i < len(terminfoNumbers) {
+			ti.Numbers[terminfoNumbers[i]] = n
+		}
+	}
+	offsets := make([]int, strCount)
+	for i := range offsets {
+		offsets[i] = r.short()
+	}
+	table := r.bytes(tableSize)
+	for i, offset := range offsets {
+		if s, ok := cString(table, offset); ok && i < len(terminfoStrings) {
+			ti.Strings[terminfoStrings[i]] = s
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.align()
+	if r.pos+10 <= len(data) {
+		parseExtended(r, ti)
+	}
+	return ti, r.err
+}
+
+// parseExtended adds the extended capabilities that follow the standard ones.  Their names are stored in the
+// string table after the string values.
+func parseExtended(r *terminfoReader, ti *Terminfo) {
+	boolCount, numCount, strCount, _, tableSize := r.short(), r.short(), r.short(), r.short(), r.short()
+	bools := r.bytes(boolCount)
+	r.align()
+	nums := make([]int, max(numCount, 0))
+	for i := range nums {
+		nums[i] = r.number()
+	}
+	values := make([]int, max(strCount, 0))
+	for i := range values {
+		values[i] = r.short()
+	}
+	names := make([]int, max(boolCount+numCount+strCount, 0))
+	for i := range names {
+		names[i] = r.short()
+	}
+	table := r.bytes(tableSize)
+	if r.err != nil {
+		return
+	}
+	base := 0
+	strs := make([]string, len(values))
+	present := make([]bool, len(values))
+	for i, offset := range values {
+		if s, ok := cString(table, offset); ok {
+			strs[i], present[i] = s, true
+			base = max(base, offset+len(s)+1)
+		}
+	}
+	name := func(i int) (string, bool) {
+		return cString(table, base+names[i])
+	}
+	for i, b := range bools {
+		if n, ok := name(i); ok && b == 1 {
+			ti.Bools[n] = true
+		}
+	}
+	for i, v := range nums {
+		if n, ok := name(boolCount + i); ok && v >= 0 {
+			ti.Numbers[n] = v
+		}
+	}
+	for i := range strs {
+		if n, ok := name(boolCount + numCount + i); ok && present[i] {
+			ti.Strings[n] = strs[i]
+		}
+	}
+}
+
+// terminfoBools are the names of the standard boolean capabilities in the order they are compiled
+var terminfoBools = []string{
+	"bw", "am", "xsb", "xhp", "xenl", "eo", "gn", "hc", "km", "hs", "in", "da", "db", "mir", "msgr", "os", "eslok",
+	"xt", "hz", "ul", "xon", "nxon", "mc5i", "chts", "nrrmc", "npc", "ndscr", "ccc", "bce", "hls", "xhpa", "crxm",
+	"daisy", "xvpa", "sam", "cpix", "lpix", "OTbs", "OTns", "OTnc", "OTMT", "OTNL", "OTpt", "OTxr",
+}
+
+// terminfoNumbers are the names of the standard numeric capabilities in the order they are compiled
+var terminfoNumbers = []string{
+	"cols", "it", "lines", "lm", "xmc", "pb", "vt", "wsl", "nlab", "lh", "lw", "ma", "wnum", "colors", "pairs",
+	"ncv", "bufsz", "spinv", "spinh", "maddr", "mjump", "mcs", "mls", "npins", "orc", "orl", "orhi", "orvi", "cps",
+	"widcs", "btns", "bitwin", "bitype", "OTug", "OTdC", "OTdN", "OTdB", "OTdT", "OTkn",
+}
+
+// terminfoStrings are the names of the standard string capabilities in the order they are compiled
+var terminfoStrings = []string{
+	"cbt", "bel", "cr", "csr", "tbc", "clear", "el", "ed", "hpa", "cmdch", "cup", "cud1", "home", "civis", "cub1",
+	"mrcup", "cnorm", "cuf1", "ll", "cuu1", "cvvis", "dch1", "dl1", "dsl", "hd", "smacs", "blink", "bold", "smcup",
+	"smdc", "dim", "smir", "invis", "prot", "rev", "smso", "smul", "ech", "rmacs", "sgr0", "rmcup", "rmdc", "rmir",
+	"rmso", "rmul", "flash", "ff", "fsl", "is1", "is2", "is3", "if", "ich1", "il1", "ip", "kbs", "ktbc", "kclr",
+	"kctab", "kdch1", "kdl1", "kcud1", "krmir", "kel", "ked", "kf0", "kf1", "kf10", "kf2", "kf3", "kf4", "kf5",
+	"kf6", "kf7", "kf8", "kf9", "khome", "kich1", "kil1", "kcub1", "kll", "knp", "kpp", "kcuf1", "kind", "kri",
+	"khts", "kcuu1", "rmkx", "smkx", "lf0", "lf1", "lf10", "lf2", "lf3", "lf4", "lf5", "lf6", "lf7", "lf8", "lf9",
+	"rmm", "smm", "nel", "pad", "dch", "dl", "cud", "ich", "indn", "il", "cub", "cuf", "rin", "cuu", "pfkey",
+	"pfloc", "pfx", "mc0", "mc4", "mc5", "rep", "rs1", "rs2", "rs3", "rf", "rc", "vpa", "sc", "ind", "ri", "sgr",
+	"hts", "wind", "ht", "tsl", "uc", "hu", "iprog", "ka1", "ka3", "kb2", "kc1", "kc3", "mc5p", "rmp", "acsc",
+	"pln", "kcbt", "smxon", "rmxon", "smam", "rmam", "xonc", "xoffc", "enacs", "smln", "rmln", "kbeg", "kcan",
+	"kclo", "kcmd", "kcpy", "kcrt", "kend", "kent", "kext", "kfnd", "khlp", "kmrk", "kmsg", "kmov", "knxt", "kopn",
+	"kopt", "kprv", "kprt", "krdo", "kref", "krfr", "krpl", "krst", "kres", "ksav", "kspd", "kund", "kBEG", "kCAN",
+	"kCMD", "kCPY", "kCRT", "kDC", "kDL", "kslt", "kEND", "kEOL", "kEXT", "kFND", "kHLP", "kHOM", "kIC", "kLFT",
+	"kMSG", "kMOV", "kNXT", "kOPT", "kPRV", "kPRT", "kRDO", "kRPL", "kRIT", "kRES", "kSAV", "kSPD", "kUND", "rfi",
+	"kf11", "kf12", "kf13", "kf14", "kf15", "kf16", "kf17", "kf18", "kf19", "kf20", "kf21", "kf22", "kf23", "kf24",
+	"kf25", "kf26", "kf27", "kf28", "kf29", "kf30", "kf31", "kf32", "kf33", "kf34", "kf35", "kf36", "kf37", "kf38",
+	"kf39", "kf40", "kf41", "kf42", "kf43", "kf44", "kf45", "kf46", "kf47", "kf48", "kf49", "kf50", "kf51", "kf52",
+	"kf53", "kf54", "kf55", "kf56", "kf57", "kf58", "kf59", "kf60", "kf61", "kf62", "kf63", "el1", "mgc", "smgl",
+	"smgr", "fln", "sclk", "dclk", "rmclk", "cwin", "wingo", "hup", "dial", "qdial", "tone", "pulse", "hook",
+	"pause", "wait", "u0", "u1", "u2", "u3", "u4", "u5", "u6", "u7", "u8", "u9", "op", "oc", "initc", "initp",
+	"scp", "setf", "setb", "cpi", "lpi", "chr", "cvr", "defc", "swidm", "sdrfq", "sitm", "slm", "smicm", "snlq",
+	"snrmq", "sshm", "ssubm", "ssupm", "sum", "rwidm", "ritm", "rlm", "rmicm", "rshm", "rsubm", "rsupm", "rum",
+	"mhpa", "mcud1", "mcub1", "mcuf1", "mvpa", "mcuu1", "porder", "mcud", "mcub", "mcuf", "mcuu", "scs", "smgb",
+	"smgbp", "smglp", "smgrp", "smgt", "smgtp", "sbim", "scsd", "rbim", "rcsd", "subcs", "supcs", "docr", "zerom",
+	"csnm", "kmous", "minfo", "reqmp", "getm", "setaf", "setab", "pfxl", "devt", "csin", "s0ds", "s1ds", "s2ds",
+	"s3ds", "smglr", "smgtb", "birep", "binel", "bicr", "colornm", "defbi", "endbi", "setcolor", "slines", "dispc",
+	"smpch", "rmpch", "smsc", "rmsc", "pctrm", "scesc", "scesa", "ehhlm", "elhlm", "elohlm", "erhlm", "ethlm",
+	"evhlm", "sgr1", "slength", "OTi2", "OTrs", "OTnl", "OTbc", "OTko", "OTma", "OTG2", "OTG3", "OTG1", "OTG4",
+	"OTGR", "OTGL", "OTGU", "OTGD", "OTGH", "OTGV", "OTGC", "meml", "memu", "box1",
+}
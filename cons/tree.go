@@ -0,0 +1,331 @@
+package cons
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TreeNode is an item of a TreeView.  Load, when set, supplies the children the first time the node is expanded,
+// so large hierarchies are only read as they are opened.
+type TreeNode struct {
+	Label string
+	// Value is for the caller's use, i.e. the account the node shows
+	Value    interface{}
+	Children []*TreeNode
+	// Load returns the children of node.  It is called once, the first time the node is expanded.
+	Load     func(node *TreeNode) ([]*TreeNode, error)
+	Expanded bool
+	loaded   bool
+	parent   *TreeNode
+}
+
+// NewTreeNode creates a node with children
+func NewTreeNode(label string, value interface{}, children ...*TreeNode) *TreeNode {
+	n := &TreeNode{Label: label, Value: value}
+	return n.Add(children...)
+}
+
+// Add appends children to the node and returns it
+func (n *TreeNode) Add(children ...*TreeNode) *TreeNode {
+	for _, child := range children {
+		child.parent = n
+	}
+	n.Children = append(n.Children, children...)
+	return n
+}
+
+// Parent returns the node's parent, or nil for a root
+func (n *TreeNode) Parent() *TreeNode {
+	return n.parent
+}
+
+// HasChildren returns true if the node has children or may load some
+func (n *TreeNode) HasChildren() bool {
+	return len(n.Children) > 0 || n.Load != nil && !n.loaded
+}
+
+// Expand shows the node's children, loading them first if Load is set and they have not been loaded
+func (n *TreeNode) Expand() error {
+	if n.Load != nil && !n.loaded {
+		children, err := n.Load(n)
+		if err != nil {
+			return err
+		}
+		n.loaded = true
+		n.Add(children...)
+	}
+	n.Expanded = len(n.Children) > 0
+	return nil
+}
+
+// Collapse hides the node's children
+func (n *TreeNode) Collapse() {
+	n.Expanded = false
+}
+
+// treeRow is a visible node and its depth
+type treeRow struct {
+	node  *TreeNode
+	depth int
+}
+
+// TreeView shows a collapsible tree in a window.  Up, Down, PgUp, PgDn, Home and End move, Right or + expands
+// the selected node or moves to its first child, Left or - collapses it or moves to its parent, typing selects
+// the next node starting with what was typed, Enter chooses a node and Esc closes the view.
+type TreeView struct {
+	// Title is drawn in the top border
+	Title string
+	Roots []*TreeNode
+	// OnSelect is called when a node is selected
+	OnSelect func(node *TreeNode)
+	// OnChoose is called when Enter is pressed on a node.  The view closes if it returns true.
+	// If it is nil the view closes choosing the node.
+	OnChoose func(node *TreeNode) bool
+	// Border is the window line style
+	Border int
+	// Foreground and Background are the window colors
+	Foreground int8
+	Background int8
+	// SelectForeground and SelectBackground are the colors of the selected node
+	SelectForeground int8
+	SelectBackground int8
+	rows             []treeRow
+	selected         int
+	top              int
+	typed            string
+	typedAt          time.Time
+	message          string
+	window           *Window
+}
+
+// NewTreeView creates a tree view showing roots
+func NewTreeView(title string, roots ...*TreeNode) *TreeView {
+	return &TreeView{Title: title, Roots: roots, Border: LineStyleSingle, Foreground: ColorBlack, Background: ColorWhite,
+		SelectForeground: ColorBrightWhite, SelectBackground: ColorBlue}
+}
+
+// Selected returns the selected node, or nil if the tree is empty
+func (v *TreeView) Selected() *TreeNode {
+	v.flatten()
+	if v.selected < len(v.rows) {
+		return v.rows[v.selected].node
+	}
+	return nil
+}
+
+// Show shows the tree in a window filling the screen and returns the chosen node, or nil if the view was closed
+// with Esc.  In line mode the visible nodes are listed and one is chosen by number.  ErrInterrupted is
+// returned for Control+C.
+func (v *TreeView) Show() (*TreeNode, error) {
+	if lineMode {
+		return v.chooseLine()
+	}
+	rows, cols, err := WindowSizeErr()
+	if err != nil {
+		return nil, err
+	}
+	return v.ShowIn(NewWindow(0, 0, rows, cols, v.Border, v.Foreground, v.Background))
+}
+
+// ShowIn shows the tree in w and returns the chosen node, restoring the screen under it afterward
+func (v *TreeView) ShowIn(w *Window) (*TreeNode, error) {
+	defer HoldScreen()()
+	w.Title = v.Title
+	v.window = w
+	w.Open()
+	defer w.Close()
+	v.flatten()
+	var selected *TreeNode
+	for {
+		if node := v.Selected(); node != selected {
+			selected = node
+			if v.OnSelect != nil && node != nil {
+				v.OnSelect(node)
+			}
+		}
+		v.draw()
+		key, err := GetKeyErr()
+		if err != nil {
+			return nil, err
+		}
+		_, _, listRows, _ := w.Inner()
+		v.message = ""
+		if !isPrintable(key) || key.Modifier&(KeyControl|KeyAlt) != 0 {
+			v.typed = ""
+		}
+		node := v.Selected()
+		switch {
+		case key.Key == KeyEscape:
+			return nil, nil
+		case key == Ctrl('C'):
+			if interrupted() {
+				return nil, ErrInterrupted
+			}
+		case node == nil:
+			Beep()
+		case key.Key == KeyUp:
+			v.selected--
+		case key.Key == KeyDown:
+			v.selected++
+		case key.Key == KeyPageUp:
+			v.selected -= listRows
+		case key.Key == KeyPageDown:
+			v.selected += listRows
+		case key.Key == KeyHome:
+			v.selected = 0
+		case key.Key == KeyEnd:
+			v.selected = len(v.rows) - 1
+		case key.Key == KeyRight || key.Key == '+':
+			switch {
+			case node.Expanded:
+				v.selected++
+			case node.HasChildren():
+				if err := node.Expand(); err != nil {
+					v.message = err.Error()
+				}
+			default:
+				Beep()
+			}
+		case key.Key == KeyLeft || key.Key == '-':
+			switch {
+			case node.Expanded:
+				node.Collapse()
+			case node.parent != nil:
+				v.selectNode(node.parent)
+			default:
+				Beep()
+			}
+		case key.Key == KeyEnter:
+			if v.OnChoose == nil || v.OnChoose(node) {
+				return node, nil
+			}
+		case isPrintable(key) && key.Modifier&(KeyControl|KeyAlt) == 0:
+			v.typeAhead(keyRune(key))
+		default:
+			Beep()
+		}
+		v.flatten()
+		v.selected = max(0, min(v.selected, len(v.rows)-1))
+		v.top = max(min(v.top, v.selected), v.selected-listRows+1)
+	}
+}
+
+// flatten lists the visible nodes
+func (v *TreeView) flatten() {
+	v.rows = v.rows[:0]
+	var walk func(nodes []*TreeNode, parent *TreeNode, depth int)
+	walk = func(nodes []*TreeNode, parent *TreeNode, depth int) {
+		for _, node := range nodes {
+			node.parent = parent
+			v.rows = append(v.rows, treeRow{node, depth})
+			if node.Expanded {
+				walk(node.Children, node, depth+1)
+			}
+		}
+	}
+	walk(v.Roots, nil, 0)
+}
+
+// selectNode selects node if it is visible
+func (v *TreeView) selectNode(node *TreeNode) {
+	for i, row := range v.rows {
+		if row.node == node {
+			v.selected = i
+		}
+	}
+}
+
+// typeAhead adds a character to the typed prefix and selects the next node starting with it
+func (v *TreeView) typeAhead(c rune) {
+	start := v.selected + 1
+	if time.Since(v.typedAt) > typeAheadPause {
+		v.typed = ""
+	} else {
+		start = v.selected
+	}
+	v.typed += string(c)
+	v.typedAt = time.Now()
+	for n := 0; n < len(v.rows); n++ {
+		index := (start + n) % len(v.rows)
+		if strings.HasPrefix(strings.ToLower(v.rows[index].node.Label), strings.ToLower(v.typed)) {
+			v.selected = index
+			return
+		}
+	}
+	Beep()
+}
+
+// treeLine returns a row's text: its indent, an expand marker and the label
+func treeLine(row treeRow) string {
+	marker := "  "
+	switch {
+	case row.node.Expanded:
+		marker = "- "
+	case row.node.HasChildren():
+		marker = "+ "
+	}
+	return strings.Repeat("  ", row.depth) + marker + row.node.Label
+}
+
+// draw draws the visible nodes and any message in the bottom border
+func (v *TreeView) draw() {
+	w := v.window
+	top, left, rows, cols := w.Inner()
+	for r := 0; r < rows; r++ {
+		index := v.top + r
+		line := ""
+		if index < len(v.rows) {
+			line = " " + treeLine(v.rows[index])
+		}
+		SetColor(v.Foreground, v.Background)
+		if index == v.selected && index < len(v.rows) {
+			SetColor(v.SelectForeground, v.SelectBackground)
+		}
+		Locate(top+r, left)
+		Print(fitText(line, cols))
+	}
+	SetColor(w.Foreground, w.Background)
+	if w.Border != LineStyleNone {
+		box := GetBoxChars(w.Border)
+		Locate(w.Row+w.Rows-1, w.Col)
+		Print(box.BottomLeft, strings.Repeat(box.Horizontal, w.Cols-2), box.BottomRight)
+		if v.message != "" {
+			Locate(w.Row+w.Rows-1, w.Col+2)
+			Print(fitText(" "+v.message+" ", min(len([]rune(v.message))+2, w.Cols-4)))
+		}
+	}
+	Locate(top+v.selected-v.top, left)
+}
+
+// chooseLine lists the visible nodes and reads the number of the chosen one
+func (v *TreeView) chooseLine() (*TreeNode, error) {
+	v.flatten()
+	if v.Title != "" {
+		fmt.Println(v.Title)
+	}
+	for i, row := range v.rows {
+		fmt.Printf("%3d %s\n", i+1, treeLine(row))
+	}
+	for {
+		fmt.Print("Choice: ")
+		line, err := readLine()
+		line = strings.TrimSpace(line)
+		if err != nil || line == "" {
+			return nil, nil
+		}
+		choice, err := strconv.Atoi(line)
+		if err != nil || choice < 1 || choice > len(v.rows) {
+			fmt.Printf("'%s' is not valid.\n", line)
+			continue
+		}
+		node := v.rows[choice-1].node
+		if v.OnSelect != nil {
+			v.OnSelect(node)
+		}
+		if v.OnChoose == nil || v.OnChoose(node) {
+			return node, nil
+		}
+	}
+}
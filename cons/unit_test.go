@@ -1,12 +1,31 @@
 package cons
 
 import (
+	"bufio"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
+	"lib/dt"
+	"lib/fixed"
+	"net"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
+// requireConsole skips tests that need a real console, i.e. when output is redirected or not on Windows
+func requireConsole(t *testing.T) {
+	if !IsTerminal() {
+		t.Skip("not a console")
+	}
+}
+
 func TestUnitClear(t *testing.T) {
+	requireConsole(t)
 	Cls()
 	if Row() != 0 || Col() != 0 {
 		t.Error("Row or Col wasn't 0.  Got (", Row(), ",", Col(), ")")
@@ -14,6 +33,7 @@ func TestUnitClear(t *testing.T) {
 
 }
 func TestUnitPos(t *testing.T) {
+	requireConsole(t)
 	Cls()
 	Locate(10, 11)
 	if Row() != 10 || Col() != 11 {
@@ -23,6 +43,7 @@ func TestUnitPos(t *testing.T) {
 }
 
 func TestUnitTitle(t *testing.T) {
+	requireConsole(t)
 	SetTitle("My Title")
 	if GetTitle() != "My Title" {
 		t.Error(fmt.Sprintf("%s%s%s", "Title wasn't 'My Title', got '", GetTitle(), "'"))
@@ -30,6 +51,7 @@ func TestUnitTitle(t *testing.T) {
 }
 
 func TestUnitFullScreen(t *testing.T) {
+	requireConsole(t)
 	if runtime.GOARCH == "amd64" {
 		SetFullScreen()
 		if IsFullScreen() {
@@ -49,6 +71,7 @@ func TestUnitFullScreen(t *testing.T) {
 }
 
 func TestUnitWindowSize(t *testing.T) {
+	requireConsole(t)
 	SetWindowSize(10, 40)
 	if Rows() != 10 || Cols() != 40 {
 		t.Error("Expected Window size (40,10), got (", Rows(), ",", Cols(), ")")
@@ -56,6 +79,7 @@ func TestUnitWindowSize(t *testing.T) {
 	SetWindowSize(25, 80)
 }
 func TestUnitWindowSizeAndBuffer(t *testing.T) {
+	requireConsole(t)
 	SetWindowAndBufferSize(10, 40, 25, 80)
 	if Rows() != 10 || Cols() != 40 {
 		t.Error("Expected Window size (10,40), got (", Rows(), ",", Cols(), ")")
@@ -65,3 +89,1282 @@ func TestUnitWindowSizeAndBuffer(t *testing.T) {
 	}
 	SetWindowSize(25, 80)
 }
+
+func TestUnitKeyMap(t *testing.T) {
+	keys := DefaultKeyMap()
+	if a := keys.Action(Key(KeyF10, KeyCapsLock)); a != ActionSave {
+		t.Error("Expected caps lock to be ignored, got", a)
+	}
+	if a := keys.Action(Key(KeyLeft, KeyShift)); a != ActionLeft {
+		t.Error("Expected shift+left to fall back to left, got", a)
+	}
+	if a := keys.Action(Key('A', KeyShift)); a != ActionNone {
+		t.Error("Expected shifted letter to be typed, got", a)
+	}
+	keys = WordStarKeyMap().With(KeyMap{Ctrl('S'): ActionSave, Key(KeyIns, 0): ActionNone})
+	if a := keys.Action(Ctrl('S')); a != ActionSave {
+		t.Error("Expected override of ^S, got", a)
+	}
+	if a := keys.Action(Key(KeyIns, 0)); a != ActionNone {
+		t.Error("Expected Ins to be unbound, got", a)
+	}
+	if a := WordStarKeyMap().Action(Ctrl('S')); a != ActionLeft {
+		t.Error("Expected With to leave the original map alone, got", a)
+	}
+}
+
+func TestUnitSelection(t *testing.T) {
+	field := NewInputField("Name", "hello world", 20)
+	if _, _, ok := selection(&field, 3); ok {
+		t.Error("Expected no selection")
+	}
+	field.selecting = true
+	field.anchor = 8
+	if start, end, ok := selection(&field, 2); !ok || start != 2 || end != 8 {
+		t.Error("Expected selection (2,8), got (", start, ",", end, ")")
+	}
+	field.anchor = 30
+	if start, end, _ := selection(&field, 6); start != 6 || end != 11 {
+		t.Error("Expected selection clipped to (6,11), got (", start, ",", end, ")")
+	}
+}
+
+func TestUnitHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h, err := LoadHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"one", "two", "two", " ", "three", "four"} {
+		if err := h.Add(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h, err = LoadHistory(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(h.Entries()) != "[two three four]" {
+		t.Error("Expected [two three four], got", h.Entries())
+	}
+	if i := h.search("t", 3); i != 1 {
+		t.Error("Expected search to find three at 1, got", i)
+	}
+}
+
+func TestUnitConsoleError(t *testing.T) {
+	err := opError("Locate", ErrNoConsole)
+	var ce *ConsoleError
+	if !errors.As(err, &ce) || ce.Op != "Locate" || !errors.Is(err, ErrNoConsole) {
+		t.Error("Expected Locate ConsoleError wrapping ErrNoConsole, got", err)
+	}
+	if err.Error() != "cons: Locate: not a console" {
+		t.Error("Unexpected message", err.Error())
+	}
+	if opError("Locate", nil) != nil {
+		t.Error("Expected nil error")
+	}
+	if !IsTerminal() && LocateErr(1, 1) == nil {
+		t.Error("Expected Locate to fail without a console")
+	}
+	if !IsTerminal() {
+		out := withStdio(t, "", func() { err = CenterErr("Title") })
+		if err == nil || out != "Title\n" {
+			t.Errorf("Expected Center to print without a console and report the error, got %q %v", out, err)
+		}
+	}
+
+	SetScreen(NewVirtualScreen(3, 20))
+	defer SetScreen(nil)
+	if err := CenterErr("Quarterly sales by region"); err != nil || Row() != 2 {
+		t.Error("Expected a wide line to be printed, got", err, Row())
+	}
+	if snap, _ := CaptureScreen(); snap.Line(0) != "Quarterly sales by r" || snap.Line(1) != "egion" {
+		t.Errorf("Expected a wide line to start at the left edge\n%s", snap.Text())
+	}
+}
+
+// withStdio runs f with stdin reading input and returns what f wrote to stdout
+func withStdio(t *testing.T, input string, f func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, reader := os.Stdout, stdinReader
+	os.Stdout, stdinReader = w, bufio.NewReader(strings.NewReader(input))
+	defer func() { os.Stdout, stdinReader = stdout, reader }()
+	f()
+	w.Close()
+	out, _ := io.ReadAll(r)
+	return string(out)
+}
+
+func TestUnitLineMode(t *testing.T) {
+	mode := GetLineMode()
+	SetLineMode(true)
+	defer SetLineMode(mode)
+
+	var c int
+	out := withStdio(t, "9\n2\n", func() { c = Choose("Menu", "", []string{"Add", "Edit", "Quit"}, LineStyleSingle, 0, 0, 0, 0, 0) })
+	if c != 2 || !strings.Contains(out, " 3. Quit\n") || !strings.Contains(out, "'9' is not valid.") {
+		t.Error("Expected choice 2 after rejecting 9, got", c, "output", out)
+	}
+
+	fields := []InputField{NewInputField("Name", "Bob", 10), NewInputField("Age", "", 3)}
+	ValidatedInputField(&fields[1], func(field *InputField, key KeyEvent) bool { return key.Key >= '0' && key.Key <= '9' }, nil)
+	var ok bool
+	out = withStdio(t, "\nx1\n42\n", func() { ok = Entry("Person", "", fields, 0, 0, 0, 0, LineStyleSingle) })
+	if !ok || FieldValue(&fields[0]) != "Bob" || FieldValue(&fields[1]) != "42" {
+		t.Error("Expected Bob/42, got", ok, FieldValue(&fields[0]), FieldValue(&fields[1]))
+	}
+	if !strings.Contains(out, "Name [Bob]: ") || !strings.Contains(out, "'x' is not valid.") {
+		t.Error("Unexpected output", out)
+	}
+	if withStdio(t, "-\n", func() { ok = StartEntry(fields[:1]) }); !ok || FieldValue(&fields[0]) != "" {
+		t.Error("Expected - to clear the field, got", ok, FieldValue(&fields[0]))
+	}
+	ValidatedInputField(&fields[1], nil, nil)
+	if withStdio(t, "\n\\-\n", func() { ok = StartEntry(fields) }); !ok || FieldValue(&fields[1]) != "-" {
+		t.Error("Expected \\- to enter -, got", ok, FieldValue(&fields[1]))
+	}
+	if withStdio(t, "", func() { ok = StartEntry(fields) }); ok {
+		t.Error("Expected end of input to cancel entry")
+	}
+}
+
+func TestUnitAnswers(t *testing.T) {
+	defer SetAnswers(GetAnswers())
+	items := []string{"Add", "Edit", "Quit"}
+	for _, text := range []string{
+		`{"Menu": [2, "quit"], "Person": {"Name": "Ann", "Age": 42}}`,
+		"# answers\nMenu: 2\nPerson:\n  Name: \"Ann\"\n  Age: 42\nMenu: Quit\n",
+	} {
+		a, err := ParseAnswers(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		SetAnswers(a)
+		fields := []InputField{NewInputField("Name", "Bob", 10), NewInputField("Age", "", 3)}
+		if c, err := ChooseErr("Menu", "", items, LineStyleSingle, 0, 0, 0, 0, 0); c != 2 || err != nil {
+			t.Error("Expected choice 2, got", c, err)
+		}
+		if ok, err := EntryErr("Person", "", fields, 0, 0, 0, 0, LineStyleSingle); !ok || err != nil || FieldValue(&fields[0]) != "Ann" || FieldValue(&fields[1]) != "42" {
+			t.Error("Expected Ann/42, got", ok, err, FieldValue(&fields[0]), FieldValue(&fields[1]))
+		}
+		if c, err := ChooseErr("Menu", "", items, LineStyleSingle, 0, 0, 0, 0, 0); c != 3 || err != nil {
+			t.Error("Expected choice 3, got", c, err)
+		}
+		var ae *AnswerError
+		if _, err := ChooseErr("Menu", "", items, LineStyleSingle, 0, 0, 0, 0, 0); !errors.As(err, &ae) {
+			t.Error("Expected AnswerError when answers run out, got", err)
+		}
+	}
+
+	a, _ := ParseAnswers(`{"Person": {"Age": "4x"}}`)
+	SetAnswers(a)
+	fields := []InputField{NewInputField("Age", "", 3)}
+	ValidatedInputField(&fields[0], func(field *InputField, key KeyEvent) bool { return key.Key >= '0' && key.Key <= '9' }, nil)
+	ok, err := EntryErr("Person", "", fields, 0, 0, 0, 0, LineStyleSingle)
+	if ok || err == nil || err.Error() != `cons: answer "4x" for "Person" field "Age": 'x' is not valid.` {
+		t.Error("Expected rejected answer, got", ok, err)
+	}
+
+	useAnswersEnv(filepath.Join(t.TempDir(), "missing.yaml"))
+	if _, err := ChooseErr("Menu", "", items, LineStyleSingle, 0, 0, 0, 0, 0); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Expected the answer file error from a menu, got", err)
+	}
+	if _, err := EntryErr("Person", "", fields, 0, 0, 0, 0, LineStyleSingle); !errors.Is(err, os.ErrNotExist) {
+		t.Error("Expected the answer file error from a form, got", err)
+	}
+	useAnswersEnv(`{"Menu": [`)
+	if _, err := ChooseErr("Menu", "", items, LineStyleSingle, 0, 0, 0, 0, 0); err == nil {
+		t.Error("Expected the answer parse error from a menu")
+	}
+}
+
+func TestUnitRecordReplay(t *testing.T) {
+	replay, err := ReadReplay(strings.NewReader(recordHeader+"\n0 104 8\n20 105 0\n0 167 0 233\n0 13 0\n"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetKeySource(replay)
+	defer SetKeySource(nil)
+	var sb strings.Builder
+	RecordKeys(&sb)
+	var keys []KeyEvent
+	for i := 0; i < 4; i++ {
+		keys = append(keys, GetKey())
+	}
+	if err := StopRecording(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(keys) != "[{104 8 0} {105 0 0} {167 0 233} {13 0 0}]" {
+		t.Error("Unexpected keys", keys)
+	}
+	again, err := ReadReplay(strings.NewReader(sb.String()), 0)
+	if err != nil || len(again.keys) != 4 || again.keys[2].key != RuneKey('é') || again.keys[3].key != Key(KeyEnter, 0) {
+		t.Error("Expected the recording to replay the same keys, got", sb.String(), err)
+	}
+
+	slow, _ := ReadReplay(strings.NewReader("50 65 0\n"), 1)
+	if key, _ := slow.Inkey(); key.Key != 0 {
+		t.Error("Expected no key before it is due, got", key)
+	}
+	if key, _ := slow.GetKey(); key.Key != 'A' {
+		t.Error("Expected A, got", key)
+	}
+	if _, err := slow.GetKey(); err != io.EOF {
+		t.Error("Expected io.EOF, got", err)
+	}
+}
+
+func TestUnitSnapshot(t *testing.T) {
+	SetScreen(NewVirtualScreen(3, 10))
+	defer SetScreen(nil)
+	Print("ab\tx\n")
+	if _, col, _ := GetScreen().Cursor(); col != 0 {
+		t.Error("Expected newline to return to column 0, got", col)
+	}
+	SetColor(ColorYellow, ColorBlue)
+	Locate(1, 2)
+	Print("<é>")
+	SetColor(ColorWhite, ColorBlack)
+	Print("\nwrapping text")
+	snap, err := CaptureScreen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Text() != "  <é>\nwrapping t\next\n" {
+		t.Errorf("Unexpected text %q", snap.Text())
+	}
+	if snap.CursorRow != 2 || snap.CursorCol != 3 {
+		t.Error("Unexpected cursor", snap.CursorRow, snap.CursorCol)
+	}
+	if c := snap.Cells[0][3]; c.Char != 'é' || c.Foreground != ColorYellow || c.Background != ColorBlue {
+		t.Error("Unexpected cell", c)
+	}
+
+	SetScreen(NewVirtualScreen(1, 5))
+	SetColor(ColorYellow, ColorBlue)
+	Print("<é>")
+	snap, _ = CaptureScreen()
+	if ansi := snap.ANSI(); ansi != "\x1b[33;44m<é>\x1b[37;40m  \x1b[0m\n" {
+		t.Errorf("Unexpected ANSI %q", ansi)
+	}
+	if html := snap.HTML("t"); !strings.Contains(html, `<span style="color:#808000;background:#000080">&lt;é&gt;</span>`) {
+		t.Error("Unexpected HTML", html)
+	}
+}
+
+// postingKeys posts a clock update from another goroutine and waits for it to be drawn before each key
+type postingKeys struct {
+	keys []KeyEvent
+}
+
+func (p *postingKeys) GetKey() (KeyEvent, error) {
+	if len(p.keys) == 0 {
+		return KeyEvent{}, io.EOF
+	}
+	drawn := make(chan struct{})
+	go Post(func() {
+		SetColor(ColorYellow, ColorBlack)
+		Locate(0, 70)
+		Printf("12:00:%02d", len(p.keys))
+		close(drawn)
+	})
+	<-drawn
+	key := p.keys[0]
+	p.keys = p.keys[1:]
+	return key, nil
+}
+
+func (p *postingKeys) Inkey() (KeyEvent, error) {
+	return p.GetKey()
+}
+
+func TestUnitPost(t *testing.T) {
+	SetScreen(NewVirtualScreen(5, 80))
+	defer SetScreen(nil)
+	SetKeySource(&postingKeys{keys: []KeyEvent{Key('o', 0), Key('k', 0), Key(KeyEnter, 0)}})
+	defer SetKeySource(nil)
+	fields := []InputField{NewInputField("Name", "", 10)}
+	PositionInputField(&fields[0], 2, 5)
+	if ok, err := StartEntryErr(fields, DefaultKeyMap()); !ok || err != nil || FieldValue(&fields[0]) != "ok" {
+		t.Fatal("Expected ok, got", ok, err, FieldValue(&fields[0]))
+	}
+	snap, _ := CaptureScreen()
+	if snap.Line(0) != strings.Repeat(" ", 70)+"12:00:01" || snap.Line(2) != "     ok" {
+		t.Errorf("Unexpected screen\n%s", snap.Text())
+	}
+	if c := snap.Cells[2][5]; c.Foreground == ColorYellow {
+		t.Error("Expected the field colors to be restored after the update, got", c)
+	}
+
+	posted := make(chan struct{})
+	go func() {
+		Post(func() {
+			Locate(4, 0)
+			Print("now")
+		})
+		close(posted)
+	}()
+	<-posted
+	if snap, _ := CaptureScreen(); snap.Line(4) != "" {
+		t.Error("Expected an update to wait for the goroutine drawing the screen, got", snap.Line(4))
+	}
+	RunPosted()
+	if snap, _ := CaptureScreen(); snap.Line(4) != "now" || snap.CursorRow != 2 {
+		t.Error("Expected RunPosted to draw the update and restore the cursor, got", snap.Line(4), snap.CursorRow)
+	}
+}
+
+func TestUnitStatusBar(t *testing.T) {
+	for key, name := range map[KeyEvent]string{Ctrl('Z'): "Ctrl+Z", Alt('f'): "Alt+F", Key(KeyTab, KeyShift): "Shift+Tab",
+		Key(KeyF10, 0): "F10", Key(KeyControlEnter, KeyControl): "Ctrl+Enter", Key('?', KeyShift): "?"} {
+		if KeyName(key) != name {
+			t.Errorf("Expected %s, got %s", name, KeyName(key))
+		}
+	}
+	if legend := formLegend(DefaultKeyMap()); legend != "F10 Save  Esc Cancel" {
+		t.Error("Unexpected form legend", legend)
+	}
+	if legend := formLegend(EmacsKeyMap()); legend != "F10 Save  Esc Cancel" {
+		t.Error("Unexpected Emacs form legend", legend)
+	}
+
+	SetScreen(NewVirtualScreen(6, 50))
+	defer SetScreen(nil)
+	bar := NewStatusBar(ColorBlack, ColorWhite).AddKey(Key(KeyF1, 0), "Help")
+	bar.SetClock("")
+	bar.SetInfo("Rec 1/3")
+	bar.Show()
+	defer func() {
+		bar.Hide()
+		RunPosted()
+	}()
+	RunPosted()
+	if GetStatusBar() != bar || Rows() != 5 {
+		t.Fatal("Expected the status bar to be shown and reserve a row, got", GetStatusBar(), Rows())
+	}
+	Cls()
+	restore := statusLegend(menuLegend(3))
+	snap, _ := CaptureScreen()
+	if line := snap.Line(5); line != " F1 Help  1-3 Choose  Enter Select       Rec 1/3" {
+		t.Errorf("Unexpected status bar %q", line)
+	}
+	if c := snap.Cells[5][0]; c.Foreground != ColorBlack || c.Background != ColorWhite {
+		t.Error("Unexpected status bar colors", c)
+	}
+	if snap.CursorRow != 0 || snap.CursorCol != 0 {
+		t.Error("Expected the cursor to stay home, got", snap.CursorRow, snap.CursorCol)
+	}
+	restore()
+	bar.Hide()
+	RunPosted()
+	snap, _ = CaptureScreen()
+	if GetStatusBar() != nil || Rows() != 6 || snap.Line(5) != "" {
+		t.Error("Expected the status bar to be hidden, got", Rows(), snap.Line(5))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			bar.Show()
+			bar.Hide()
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		RunPosted()
+		Rows()
+		GetStatusBar()
+	}
+	<-done
+	RunPosted()
+}
+
+func TestUnitProgress(t *testing.T) {
+	SetScreen(NewVirtualScreen(8, 40))
+	defer SetScreen(nil)
+	Print("Files")
+	Locate(1, 0)
+	p := NewProgress("Copy", 200)
+	if Row() != 2 {
+		t.Error("Expected the cursor below the progress bar, got", Row())
+	}
+	p.Set(50)
+	RunPosted()
+	snap, _ := CaptureScreen()
+	if line := snap.Line(1); !strings.HasPrefix(line, "Copy ██") || !strings.Contains(line, "░  25% ") || !strings.Contains(line, "ETA") {
+		t.Errorf("Unexpected progress %q", line)
+	}
+	p.Done()
+	RunPosted()
+	if snap, _ = CaptureScreen(); !strings.Contains(snap.Line(1), "100%") || strings.Contains(snap.Line(1), "ETA") {
+		t.Errorf("Unexpected finished progress %q", snap.Line(1))
+	}
+
+	w := NewWindow(2, 5, 4, 30, LineStyleSingle, ColorBlack, ColorCyan)
+	w.Title = "Disk"
+	w.Open()
+	g := NewGauge("Used", 0, 100, "%.0f%%")
+	g.PlaceIn(w, 0)
+	g.Set(50)
+	RunPosted()
+	snap, _ = CaptureScreen()
+	if snap.Line(2) != "     ┌─────────── Disk ───────────┐" || snap.Line(3) != "     │ Used █████████░░░░░░░░ 50% │" {
+		t.Errorf("Unexpected window\n%s", snap.Text())
+	}
+	w.Close()
+	if snap, _ = CaptureScreen(); snap.Line(2) != "" || !strings.HasPrefix(snap.Line(1), "Copy ████") {
+		t.Errorf("Expected the window to be closed\n%s", snap.Text())
+	}
+
+	SetScreen(NewVirtualScreen(4, 30))
+	Locate(0, 0)
+	p = NewProgress("Importing customer ledger records", 100)
+	p.Add(10)
+	g = NewGauge("Remaining disk space on volume", 0, 100, "%.0f%%")
+	g.Set(50)
+	RunPosted()
+	if snap, _ = CaptureScreen(); snap.Line(0) != "Importing customer ledger rec" || snap.Line(1) != "Remaining disk space on volum" {
+		t.Errorf("Expected labels wider than the screen to be truncated\n%s", snap.Text())
+	}
+
+	mode := GetLineMode()
+	SetLineMode(true)
+	defer SetLineMode(mode)
+	out := withStdio(t, "", func() {
+		p := NewProgress("Load", 10)
+		for i := 0; i < 10; i++ {
+			p.Add(1)
+		}
+		p.Done()
+		s := NewSpinner("Index")
+		s.Start()
+		s.Stop("done")
+	})
+	if !strings.HasPrefix(out, "Load   0%\n") || !strings.Contains(out, "Load 100% ") || !strings.HasSuffix(out, "Index...\nIndex done\n") ||
+		strings.Count(out, "\n") != 4 {
+		t.Errorf("Unexpected log %q", out)
+	}
+}
+
+func TestUnitPager(t *testing.T) {
+	SetScreen(NewVirtualScreen(5, 20))
+	defer SetScreen(nil)
+	Print("under")
+	replay, _ := ReadReplay(strings.NewReader("0 47 0\n0 88 8\n0 89 8\n0 13 0\n0 113 0\n"), 0)
+	SetKeySource(replay)
+	defer SetKeySource(nil)
+	p := NewTextPager("", "abc xyz\t|\n")
+	if err := p.Show(); err != nil || string(p.pattern) != "XY" {
+		t.Fatal("Expected a search for XY, got", err, string(p.pattern))
+	}
+	snap, _ := CaptureScreen()
+	if snap.Line(0) != "under" {
+		t.Errorf("Expected the screen to be restored\n%s", snap.Text())
+	}
+
+	p.window = NewWindow(0, 0, 5, 20, LineStyleSingle, p.Foreground, p.Background)
+	p.window.Draw()
+	p.draw()
+	snap, _ = CaptureScreen()
+	if snap.Line(1) != "│abc xyz |         │" {
+		t.Errorf("Unexpected pager\n%s", snap.Text())
+	}
+	for col, lit := range []bool{false, false, false, false, false, true, true, false} {
+		if c := snap.Cells[1][col]; (c.Background == ColorBrightYellow) != lit {
+			t.Error("Unexpected highlight at", col, c)
+		}
+	}
+}
+
+func TestUnitDatePicker(t *testing.T) {
+	date := time.Date(2024, time.January, 31, 0, 0, 0, 0, time.Local)
+	if got := addMonths(date, 1); got.Month() != time.February || got.Day() != 29 {
+		t.Error("Expected February 29, got", got)
+	}
+	if got := addMonths(date, -13); got.Year() != 2022 || got.Month() != time.December || got.Day() != 31 {
+		t.Error("Expected December 31 2022, got", got)
+	}
+	if got := parseDate("2024-03-05", "01/02/2006"); got.Month() != time.March || got.Day() != 5 {
+		t.Error("Expected dt.Stod formats to be read, got", got)
+	}
+	if got := parseDate("soon", "01/02/2006"); !got.Equal(dt.Today()) {
+		t.Error("Expected today for an invalid date, got", got)
+	}
+	if GetKeyMap().Action(Key(KeyDown, KeyAlt)) != ActionPopup {
+		t.Error("Expected Alt+Down to open the popup")
+	}
+}
+
+func TestUnitCalculator(t *testing.T) {
+	run := func(keys string) *calculator {
+		c := &calculator{decimals: 2}
+		for i := 0; i < len(keys); i++ {
+			c.press(keys[i])
+		}
+		return c
+	}
+	for keys, want := range map[string]string{"100+25=": "125.00", "200+10%": "220.00", "50*10%": "5.00", "7-9*2=": "-4.00",
+		"10/4=": "2.50", "2+3*": "5.00", "12.5N+1=": "-11.50", "9\b8+1=": "9.00", "5+E2=": "7.00", "3+4C": "0.00"} {
+		if c := run(keys); c.format(c.total) != want {
+			t.Errorf("%s: expected %s, got %s", keys, want, c.format(c.total))
+		}
+	}
+	if c := run("100+25="); strings.Join(c.tape, "|") != "100.00 +|25.00 =|125.00 T" {
+		t.Error("Unexpected tape", c.tape)
+	}
+	if c := run("1/0="); c.message != "Divide by zero" {
+		t.Error("Expected divide by zero, got", c.message)
+	}
+	defer func() { calculatorMemory = fixed.Fixed{} }()
+	if c := run("L6P4PR="); c.format(c.total) != "10.00" {
+		t.Error("Expected memory recall of 10, got", c.format(c.total))
+	}
+
+	if c := run("9999999999999999"); c.entry != "99999999999999" {
+		t.Error("Expected the entry to stop at 14 digits, got", c.entry)
+	}
+	if c := run("99999999999999="); c.format(c.total) != "99,999,999,999,999.00" {
+		t.Error("Expected the largest entry, got", c.format(c.total))
+	}
+
+	for value, want := range map[string]string{"-1.5": "-1.50", ".25": "0.25", "1234.56789": "1,234.56", "0": "0.00", "+2": "2.00",
+		"-922337203685477.5807": "-922,337,203,685,477.58"} {
+		f, err := fixed.FromString(value)
+		if got := f.ToString(2, ',', 0); err != nil || got != want {
+			t.Errorf("%s: expected %s, got %s %v", value, want, got, err)
+		}
+	}
+	for _, value := range []string{"", "-", "+", "-+5", "+-5", "--5", ".", "1.-5", "1.2.3", "999999999999999", "922337203685477.5808"} {
+		if f, err := fixed.FromString(value); err == nil {
+			t.Errorf("%q: expected an error, got %s", value, f.ToString(2, ',', 0))
+		}
+	}
+}
+
+func TestUnitLineEditorValue(t *testing.T) {
+	SetScreen(NewVirtualScreen(2, 20))
+	defer SetScreen(nil)
+	replay, _ := ReadReplay(strings.NewReader("0 8 0\n0 120 0\n0 167 0 233\n0 13 0\n"), 0)
+	SetKeySource(replay)
+	defer SetKeySource(nil)
+	editor := LineEditor{Value: "*.csv"}
+	if line, err := editor.Read(); line != "*.csxé" || err != nil {
+		t.Error("Expected *.csxé, got", line, err)
+	}
+}
+
+func TestUnitTreeNodeLoad(t *testing.T) {
+	calls := 0
+	node := NewTreeNode("Expenses", nil)
+	node.Load = func(n *TreeNode) ([]*TreeNode, error) {
+		calls++
+		if calls == 1 {
+			return nil, errors.New("offline")
+		}
+		return []*TreeNode{NewTreeNode("Rent", nil)}, nil
+	}
+	if err := node.Expand(); err == nil || node.Expanded || !node.HasChildren() {
+		t.Fatal("Expected the load error to leave the node collapsed, got", err, node.Expanded)
+	}
+	if err := node.Expand(); err != nil || !node.Expanded || node.Children[0].Parent() != node {
+		t.Fatal("Expected the loaded child, got", err, node.Expanded)
+	}
+	node.Collapse()
+	if err := node.Expand(); err != nil || calls != 2 || len(node.Children) != 1 {
+		t.Error("Expected children to load once, got", calls, len(node.Children))
+	}
+}
+
+func TestUnitLayout(t *testing.T) {
+	if got := share(10, 1, []int{3, 0, 0}); fmt.Sprint(got) != "[3 2 3]" {
+		t.Error("Expected [3 2 3], got", got)
+	}
+	if got := share(4, 0, []int{3, 3}); fmt.Sprint(got) != "[3 1]" {
+		t.Error("Expected [3 1], got", got)
+	}
+	labels := []Widget{NewLabel("a"), NewLabel("b"), NewLabel("c")}
+	grid := NewGrid(2, labels...)
+	grid.Place(0, 0, 10, 40)
+	if l := labels[2].(*Label); l.Row != 5 || l.Col != 0 || l.Rows != 5 || l.Cols != 20 {
+		t.Error("Unexpected grid cell", l.Base)
+	}
+	grid.Place(0, 0, 4, 20)
+	if l := labels[1].(*Label); l.Row != 0 || l.Col != 10 || l.Rows != 2 || l.Cols != 10 {
+		t.Error("Expected the grid to adapt to its size, got", l.Base)
+	}
+	box := NewHBox(NewButton("OK", nil), NewVBox(NewLabel("two\nlines")))
+	box.Border = LineStyleSingle
+	if rows, cols := box.Size(); rows != 4 || cols != 13 {
+		t.Error("Expected 4x13, got", rows, cols)
+	}
+}
+
+func TestUnitMarkup(t *testing.T) {
+	if got := StripMarkup("[yellow:blue]Total:[-] [[12.00] [1] [:red]x[-][-]"); got != "Total: [12.00] [1] x" {
+		t.Errorf("Unexpected stripped text %q", got)
+	}
+	var sb strings.Builder
+	plain := NewPlainMarkupWriter(&sb)
+	fmt.Fprint(plain, "a[re")
+	fmt.Fprint(plain, "d]b[-]c[")
+	if sb.String() != "abc" {
+		t.Errorf("Expected a tag split across writes to be held, got %q", sb.String())
+	}
+	plain.Flush()
+	if sb.String() != "abc[" {
+		t.Errorf("Expected Flush to write the held text, got %q", sb.String())
+	}
+	if fmt.Fprint(plain, "[red]x"); len(plain.stack) != 0 {
+		t.Error("Expected no style pushed when markup is stripped, got", plain.stack)
+	}
+	if !IsTerminal() {
+		out := withStdio(t, "", func() {
+			w := NewMarkupWriter()
+			fmt.Fprint(w, "[red]x[-]y")
+			w.Flush()
+		})
+		if out != "xy" {
+			t.Errorf("Expected markup stripped when stdout is not a terminal, got %q", out)
+		}
+	}
+
+	SetScreen(NewVirtualScreen(2, 20))
+	defer SetScreen(nil)
+	SetColor(ColorWhite, ColorBlack)
+	w := NewMarkupWriter()
+	fmt.Fprint(w, "a[brightyellow:blue]b[:red]c[-]d[-]e[green]f")
+	w.Flush()
+	snap, _ := CaptureScreen()
+	if snap.Line(0) != "abcdef" {
+		t.Errorf("Unexpected text %q", snap.Line(0))
+	}
+	want := [][2]int8{{ColorWhite, ColorBlack}, {ColorBrightYellow, ColorBlue}, {ColorBrightYellow, ColorRed},
+		{ColorBrightYellow, ColorBlue}, {ColorWhite, ColorBlack}, {ColorGreen, ColorBlack}}
+	for col, colors := range want {
+		if c := snap.Cells[0][col]; c.Foreground != colors[0] || c.Background != colors[1] {
+			t.Error("Unexpected colors at", col, c)
+		}
+	}
+	if fg, bg := GetColor(); fg != ColorWhite || bg != ColorBlack {
+		t.Error("Expected Flush to restore the colors, got", fg, bg)
+	}
+}
+
+func TestUnitBoxChars(t *testing.T) {
+	for style, want := range map[int]string{
+		LineStyleSingle: "┌┐└┘─│┬┴├┤┼", LineStyleDouble: "╔╗╚╝═║╦╩╠╣╬", LineStyleHeavy: "┏┓┗┛━┃┳┻┣┫╋",
+		LineStyleRounded: "╭╮╰╯─│┬┴├┤┼", LineStyleASCII: "++++-|+++++", LineStyleDoubleHorizontal: "╒╕╘╛═│╤╧╞╡╪",
+		LineStyleDoubleVertical: "╓╖╙╜─║╥╨╟╢╫", LineStyleNone: "           ",
+	} {
+		b := GetBoxChars(style)
+		got := b.TopLeft + b.TopRight + b.BottomLeft + b.BottomRight + b.Horizontal + b.Vertical + b.TopJoin +
+			b.BottomJoin + b.LeftJoin + b.RightJoin + b.Cross
+		if got != want {
+			t.Errorf("Style %d expected %s, got %s", style, want, got)
+		}
+	}
+	lines := NewLines().Box(0, 0, 5, 9, LineStyleDouble).Horizontal(2, 0, 9, LineStyleSingle).
+		Vertical(0, 4, 5, LineStyleSingle).Vertical(1, 6, 3, LineStyleHeavy)
+	var rows []string
+	for r := 0; r < 5; r++ {
+		var sb strings.Builder
+		for c := 0; c < 9; c++ {
+			sb.WriteRune(lines.Rune(r, c))
+		}
+		rows = append(rows, sb.String())
+	}
+	if got := strings.Join(rows, "\n"); got != "╔═══╤═══╗\n║   │ ╻ ║\n╟───┼─╂─╢\n║   │ ╹ ║\n╚═══╧═══╝" {
+		t.Errorf("Unexpected junctions\n%s", got)
+	}
+	if r := joinRune("3232", false); r != '╫' {
+		t.Errorf("Expected heavy crossing double to fall back to ╫, got %c", r)
+	}
+
+	SetScreen(NewVirtualScreen(3, 20))
+	defer SetScreen(nil)
+	NewLines().Horizontal(1, 15, 10, LineStyleSingle).Box(-1, -1, 3, 3, LineStyleSingle).Draw()
+	snap, _ := CaptureScreen()
+	if got := snap.Text(); got != " │\n─┘             ╶────\n\n" {
+		t.Errorf("Expected lines off the screen to be left out, got\n%s", got)
+	}
+	if got := string(asciiWeights([]byte("3201"))); got != "4404" {
+		t.Error("Expected ASCII weights 4404, got", got)
+	}
+}
+
+func TestUnitTerminfo(t *testing.T) {
+	var data []byte
+	shorts := func(values ...int) {
+		for _, v := range values {
+			data = binary.LittleEndian.AppendUint16(data, uint16(int16(v)))
+		}
+	}
+	names := "test|Test terminal\x00"
+	shorts(0o432, len(names), 2, 14, 2, 2)
+	data = append(data, names...)
+	data = append(data, 0, 1, 0)
+	shorts(80, -1, 24, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, 8)
+	shorts(-1, 0)
+	data = append(data, "\a\x00"...)
+	// Extended: Tc and XM, with the names after the string values
+	table := "\x1b[?1000h\x00Tc\x00XM\x00"
+	shorts(1, 0, 1, 3, len(table))
+	data = append(data, 1, 0)
+	shorts(0, 0, 3)
+	data = append(data, table...)
+	ti, err := ParseTerminfo(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ti.Names) != 2 || ti.Names[0] != "test" || !ti.Bools["am"] || ti.Bools["bw"] || ti.Numbers["cols"] != 80 ||
+		ti.Numbers["lines"] != 24 || ti.Numbers["colors"] != 8 || ti.Strings["bel"] != "\a" || ti.Strings["cbt"] != "" {
+		t.Errorf("Unexpected standard capabilities %+v", ti)
+	}
+	if !ti.Bools["Tc"] || ti.Strings["XM"] != "\x1b[?1000h" {
+		t.Errorf("Unexpected extended capabilities %+v", ti)
+	}
+	if _, err := ParseTerminfo(data[:20]); err != ErrBadTerminfo {
+		t.Errorf("Expected ErrBadTerminfo for a short entry, got %v", err)
+	}
+	if _, err := ParseTerminfo([]byte("not terminfo")); err != ErrBadTerminfo {
+		t.Errorf("Expected ErrBadTerminfo for bad magic, got %v", err)
+	}
+}
+
+func TestUnitCapabilities(t *testing.T) {
+	noTerminfo := func(string) (*Terminfo, error) { return nil, errors.New("none") }
+	detect := func(env ...string) Capabilities {
+		return detectCapabilities(func(name string) string {
+			for i := 0; i+1 < len(env); i += 2 {
+				if env[i] == name {
+					return env[i+1]
+				}
+			}
+			return ""
+		}, noTerminfo)
+	}
+	if c := detect("TERM", "dumb", "LANG", "en_US.UTF-8"); c.Colors != 0 || !c.UTF8 || c.LineDrawing || c.Terminfo != nil {
+		t.Errorf("Unexpected dumb capabilities %+v", c)
+	}
+	if c := detect("TERM", "xterm-256color", "LANG", "C"); c.Colors != 256 || c.UTF8 || !c.LineDrawing || !c.Title ||
+		!c.Mouse {
+		t.Errorf("Unexpected xterm capabilities %+v", c)
+	}
+	if c := detect("TERM", "xterm", "COLORTERM", "truecolor", "LC_ALL", "de_DE.utf8", "LANG", "C"); c.Colors !=
+		ColorsTrueColor || !c.UTF8 {
+		t.Errorf("Unexpected truecolor capabilities %+v", c)
+	}
+	if c := detect("TERM", "vt100", "LC_CTYPE", "C", "LANG", "en_US.UTF-8"); c.Colors != 0 || c.UTF8 || !c.LineDrawing ||
+		c.Title {
+		t.Errorf("Unexpected vt100 capabilities %+v", c)
+	}
+	if c := detect("TERM", "screen.xterm-256color"); c.Colors != 256 || !c.Title || !c.Terminfo.Bools["hs"] {
+		t.Errorf("Unexpected screen capabilities %+v", c)
+	}
+	if c := detect("TERM", "linux"); c.Colors != 8 || c.Title || c.Mouse {
+		t.Errorf("Unexpected linux capabilities %+v", c)
+	}
+	if c := detect("TERM", "unknown"); c.Terminfo != nil || c.Colors != 0 {
+		t.Errorf("Unexpected unknown capabilities %+v", c)
+	}
+
+	saved := GetCapabilities()
+	defer SetCapabilities(saved)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		SetCapabilities(Capabilities{Term: "dumb"})
+	}()
+	GetCapabilities()
+	<-done
+	if c := GetCapabilities(); c.Term != "dumb" {
+		t.Errorf("Expected the capabilities set, got %+v", c)
+	}
+}
+
+func TestUnitTelnetKeys(t *testing.T) {
+	for input, want := range map[string]KeyEvent{
+		"a": Key('a', 0), "\x7f": Key(KeyBackspace, 0), "\r": Key(KeyEnter, 0), "\x03": Ctrl('C'),
+		"\x1b": Key(KeyEscape, 0), "\x1bf": Alt('f'), "\x1b[A": Key(KeyUp, 0), "\x1bOB": Key(KeyDown, 0),
+		"\x1b[1;5C": Key(KeyRight, KeyControl), "\x1b[3~": Key(KeyDel, 0), "\x1b[6;2~": Key(KeyPageDown, KeyShift),
+		"\x1b[Z": Key(KeyTab, KeyShift), "\x1bOP": Key(KeyF1, 0), "\x1b[24~": Key(KeyF12, 0),
+		"é": RuneKey('é'), "€": RuneKey('€'),
+	} {
+		s := &TelnetSession{input: make(chan byte), pending: []byte(input[1:])}
+		if key, ok := s.decodeKey(input[0]); !ok || key != want {
+			t.Errorf("%q expected %s, got %s", input, KeyName(want), KeyName(key))
+		}
+	}
+	s := &TelnetSession{input: make(chan byte), pending: []byte("(")}
+	if _, ok := s.decodeKey(0xc3); ok || len(s.pending) != 0 {
+		t.Error("Expected invalid UTF-8 to be skipped")
+	}
+}
+
+func TestUnitTelnet(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("No loopback listener:", err)
+	}
+	defer l.Close()
+	result := make(chan string, 1)
+	go ServeTelnet(l, func(s *TelnetSession) error {
+		rows, cols, _ := WindowSizeErr()
+		Print("Operator sign on")
+		fields := []InputField{NewInputField("Name", "", 10)}
+		PositionInputField(&fields[0], 2, 5)
+		ok, err := StartEntryErr(fields, DefaultKeyMap())
+		result <- fmt.Sprint(rows, "x", cols, " ", ok, " ", err, " ", FieldValue(&fields[0]))
+		return err
+	})
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	want := []byte{telnetIAC, telnetWill, telnetEcho, telnetIAC, telnetWill, telnetSGA, telnetIAC, telnetDo, telnetSGA,
+		telnetIAC, telnetDo, telnetNAWS}
+	got := make([]byte, len(want))
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := io.ReadFull(conn, got); err != nil || string(got) != string(want) {
+		t.Fatalf("Expected negotiation %v, got %v %v", want, got, err)
+	}
+	// Agree, report a 10 row by 40 column window, offer line mode and type a name
+	conn.Write([]byte{telnetIAC, telnetDo, telnetEcho, telnetIAC, telnetWill, telnetSGA, telnetIAC, telnetWill, telnetNAWS,
+		telnetIAC, telnetSB, telnetNAWS, 0, 40, 0, 10, telnetIAC, telnetSE, telnetIAC, telnetWill, 34})
+	conn.Write([]byte("abc\x7fd\x1b[D\x1b[C\r\x00"))
+	output, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := <-result; got != "10x40 true <nil> abd" {
+		t.Errorf("Unexpected session result %s", got)
+	}
+	for _, want := range []string{string([]byte{telnetIAC, telnetDont, 34}), "\x1b[3;6H", "Operator sign on", "abd"} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("Expected output to contain %q", want)
+		}
+	}
+}
+
+func TestUnitTelnetWait(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip("No loopback listener:", err)
+	}
+	defer l.Close()
+	errs := make(chan error, 2)
+	SetTelnetErrorHandler(func(s *TelnetSession, err error) { errs <- err })
+	defer SetTelnetErrorHandler(nil)
+	started, release := make(chan struct{}), make(chan struct{})
+	go ServeTelnet(l, func(s *TelnetSession) error {
+		close(started)
+		<-release
+		return errors.New("first session failed")
+	})
+	first, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	go io.Copy(io.Discard, first)
+	<-started
+	second, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second.SetDeadline(time.Now().Add(5 * time.Second))
+	var output []byte
+	for !strings.Contains(string(output), "Waiting for another session") {
+		buf := make([]byte, 256)
+		n, err := second.Read(buf)
+		if err != nil {
+			t.Fatal("Expected the second session to wait, got", string(output), err)
+		}
+		output = append(output, buf[:n]...)
+	}
+	second.Close()
+	if err := <-errs; err != ErrSessionClosed {
+		t.Error("Expected the waiting session to end when its client disconnects, got", err)
+	}
+	close(release)
+	if err := <-errs; err == nil || err.Error() != "first session failed" {
+		t.Error("Expected the handler error to be reported, got", err)
+	}
+}
+
+// slowKeys returns each key after a delay, calling before first so tests can see the screen while idle
+type slowKeys struct {
+	delay  time.Duration
+	keys   []KeyEvent
+	before func()
+}
+
+func (s *slowKeys) GetKey() (KeyEvent, error) {
+	if len(s.keys) == 0 {
+		return KeyEvent{}, io.EOF
+	}
+	time.Sleep(s.delay)
+	if s.before != nil {
+		s.before()
+	}
+	key := s.keys[0]
+	s.keys = s.keys[1:]
+	return key, nil
+}
+
+func (s *slowKeys) Inkey() (KeyEvent, error) {
+	return s.GetKey()
+}
+
+func TestUnitIdle(t *testing.T) {
+	SetScreen(NewVirtualScreen(5, 30))
+	defer SetScreen(nil)
+	defer SetIdleTimeout(0, nil)
+	defer SetScreenSaver(0, ScreenSaverNone)
+	defer SetIdleCancel(0)
+	defer func(tick time.Duration) { saverTick = tick }(saverTick)
+	saverTick = 20 * time.Millisecond
+	SetColor(ColorYellow, ColorBlue)
+	Cls()
+	Locate(1, 2)
+	Print("Order 1234")
+	Locate(3, 4)
+	original, _ := CaptureScreen()
+
+	calls := 0
+	SetIdleTimeout(10*time.Millisecond, func() { calls++ })
+	SetScreenSaver(30*time.Millisecond, ScreenSaverDim)
+	var saver *Snapshot
+	keys := &slowKeys{delay: 200 * time.Millisecond, keys: []KeyEvent{Key('x', 0), Key('y', 0)}}
+	keys.before = func() {
+		if saver == nil {
+			saver, _ = CaptureScreen()
+			keys.delay = 0
+		}
+	}
+	SetKeySource(keys)
+	defer SetKeySource(nil)
+	if key, err := GetKeyErr(); err != nil || key != Key('y', 0) {
+		t.Fatalf("Expected the key waking the screen saver to be ignored, got %s %v", KeyName(key), err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the idle callback once, got %d", calls)
+	}
+	if saver == nil || saver.Cells[1][2] != (Cell{Char: 'O', Foreground: ColorGray, Background: ColorBlack}) ||
+		!strings.Contains(saver.Text(), ":") {
+		t.Errorf("Expected a dimmed screen with a clock, got\n%s", saver.Text())
+	} else if line := saver.Line(2); strings.Index(line, ":") == 13 && line[11] != ' ' {
+		t.Errorf("Expected the clock to move from the middle of the screen\n%s", saver.Text())
+	}
+	if restored, _ := CaptureScreen(); restored.Text() != original.Text() || restored.Cells[1][2] != original.Cells[1][2] ||
+		restored.CursorRow != 3 || restored.CursorCol != 4 {
+		t.Errorf("Expected the screen restored, got\n%s", restored.Text())
+	}
+	if fg, bg := GetColor(); fg != ColorYellow || bg != ColorBlue {
+		t.Errorf("Expected the colors restored, got %d %d", fg, bg)
+	}
+
+	SetScreenSaver(0, ScreenSaverNone)
+	SetIdleCancel(30 * time.Millisecond)
+	SetKeySource(&slowKeys{delay: 100 * time.Millisecond, keys: []KeyEvent{Key('z', 0)}})
+	fields := []InputField{NewInputField("Name", "", 10)}
+	if ok, err := StartEntryErr(fields, DefaultKeyMap()); ok || !errors.Is(err, ErrIdleTimeout) {
+		t.Fatal("Expected the form canceled with ErrIdleTimeout, got", ok, err)
+	}
+	SetIdleCancel(0)
+	if key, err := GetKeyErr(); err != nil || key != Key('z', 0) {
+		t.Errorf("Expected the key pressed after the timeout, got %s %v", KeyName(key), err)
+	}
+
+	SetIdleCancel(30 * time.Millisecond)
+	first := &slowKeys{delay: 100 * time.Millisecond, keys: []KeyEvent{Key('1', 0), Key('4', 0)}}
+	SetKeySource(first)
+	if StartEntry(fields) {
+		t.Error("Expected StartEntry to be canceled")
+	}
+	SetKeySource(&slowKeys{delay: 100 * time.Millisecond, keys: []KeyEvent{Key('2', 0)}})
+	if c := Choose("Menu", "", []string{"Add", "Edit"}, LineStyleSingle, 0, 0, 0, 0, 0); c != 0 {
+		t.Error("Expected Choose to be canceled, got", c)
+	}
+	SetIdleCancel(0)
+	SetKeySource(&slowKeys{keys: []KeyEvent{Key('3', 0)}})
+	if key, err := GetKeyErr(); err != nil || key != Key('3', 0) {
+		t.Errorf("Expected a key from the new source, got %s %v", KeyName(key), err)
+	}
+	SetKeySource(first)
+	for _, want := range []KeyEvent{Key('1', 0), Key('4', 0)} {
+		if key, err := GetKeyErr(); err != nil || key != want {
+			t.Errorf("Expected the key read before the source changed, then the next, got %s %v", KeyName(key), err)
+		}
+	}
+}
+
+func TestUnitFieldUndo(t *testing.T) {
+	SetScreen(NewVirtualScreen(3, 40))
+	defer SetScreen(nil)
+	field := NewInputField("Name", "abc", 10)
+	e := fieldEditor{field: &field, offset: 3}
+	e.key(ActionNone, Key('d', 0))
+	e.key(ActionNone, Key('e', 0))
+	e.key(ActionBackspace, Key(KeyBackspace, 0))
+	var got []string
+	for _, action := range []Action{ActionUndo, ActionUndo, ActionUndo, ActionRedo, ActionRedo, ActionRedo} {
+		e.key(action, KeyEvent{})
+		got = append(got, field.value)
+	}
+	if fmt.Sprint(got) != "[abcde abc abc abcde abcd abcd]" {
+		t.Error("Expected typing undone as one edit, got", got)
+	}
+
+	long := NewInputField("Notes", strings.Repeat("x", 150), 200)
+	e = fieldEditor{field: &long, offset: 150}
+	for i := 0; i < 120; i++ {
+		e.key(ActionBackspace, Key(KeyBackspace, 0))
+	}
+	if len(long.undo) != maxUndo {
+		t.Errorf("Expected %d undo entries, got %d", maxUndo, len(long.undo))
+	}
+	for i := 0; i < 120; i++ {
+		e.key(ActionUndo, KeyEvent{})
+	}
+	if len(long.value) != 130 {
+		t.Errorf("Expected the last %d edits undone to 130 characters, got %d", maxUndo, len(long.value))
+	}
+
+	revert := NewInputField("City", "changed", 10)
+	revert.original = "orig"
+	e = fieldEditor{field: &revert, offset: 2}
+	e.key(ActionRevertField, KeyEvent{})
+	if revert.value != "orig" || e.offset != 0 {
+		t.Errorf("Expected the field reverted to orig, got %q at %d", revert.value, e.offset)
+	}
+	e.key(ActionUndo, KeyEvent{})
+	if revert.value != "changed" {
+		t.Errorf("Expected the revert undone, got %q", revert.value)
+	}
+}
+
+func TestUnitFieldClipboard(t *testing.T) {
+	SetScreen(NewVirtualScreen(3, 40))
+	defer SetScreen(nil)
+	defer SetClipboard("")
+	field := NewInputField("Code", "abcdef", 8)
+	e := fieldEditor{field: &field, offset: 0}
+	e.key(ActionEnd, KeyEvent{})
+	e.key(ActionHome, Key(KeyHome, KeyShift))
+	e.key(ActionRight, Key(KeyRight, KeyShift))
+	if e.key(ActionCopy, KeyEvent{}); GetClipboard() != "bcdef" || field.value != "abcdef" {
+		t.Errorf("Expected bcdef copied, got %q", GetClipboard())
+	}
+	SetClipboard("123456")
+	e.key(ActionEnd, KeyEvent{})
+	if e.key(ActionPaste, KeyEvent{}); field.value != "abcdef12" || e.offset != 8 {
+		t.Errorf("Expected the paste cut to the field size, got %q at %d", field.value, e.offset)
+	}
+	if e.key(ActionCut, KeyEvent{}); GetClipboard() != "abcdef12" || field.value != "" {
+		t.Errorf("Expected the whole field cut, got %q leaving %q", GetClipboard(), field.value)
+	}
+
+	digits := NewInputField("Qty", "12", 5)
+	ValidatedInputField(&digits, func(field *InputField, key KeyEvent) bool {
+		return key.Key >= '0' && key.Key <= '9'
+	}, nil)
+	e = fieldEditor{field: &digits, offset: 2}
+	SetClipboard("3a")
+	if e.key(ActionPaste, KeyEvent{}); digits.value != "12" || len(digits.undo) != 0 {
+		t.Errorf("Expected the paste rejected, got %q", digits.value)
+	}
+	SetClipboard("34")
+	if e.key(ActionPaste, KeyEvent{}); digits.value != "1234" {
+		t.Errorf("Expected 34 pasted, got %q", digits.value)
+	}
+}
+
+func TestUnitLineEditorHistory(t *testing.T) {
+	SetScreen(NewVirtualScreen(3, 40))
+	defer SetScreen(nil)
+	defer SetKeySource(nil)
+	read := func(keys ...KeyEvent) string {
+		history := NewHistory(10)
+		for _, line := range []string{"list orders", "post batch", "list customers"} {
+			history.Add(line)
+		}
+		SetKeySource(&slowKeys{keys: keys})
+		editor := LineEditor{History: history}
+		line, err := editor.Read()
+		if err != nil {
+			t.Fatal(KeyName(keys[0]), len(keys), err)
+		}
+		return line
+	}
+	up, down := Key(KeyUp, 0), Key(KeyDown, 0)
+	enter, search := Key(KeyEnter, 0), Ctrl('R')
+	if line := read(up, up, up, down, enter); line != "post batch" {
+		t.Error("Expected up and down to recall post batch, got", line)
+	}
+	if line := read(Key('x', 0), up, down, enter); line != "x" {
+		t.Error("Expected down past the newest entry to restore the line being typed, got", line)
+	}
+	// Control+R finds the newest match, again finds an older one and Backspace widens the query.  Enter ends
+	// the search with the match in the line and a second Enter accepts it.
+	if line := read(search, Key('l', 0), Key('i', 0), enter, enter); line != "list customers" {
+		t.Error("Expected search to find list customers, got", line)
+	}
+	if line := read(search, Key('l', 0), Key('i', 0), search, enter, enter); line != "list orders" {
+		t.Error("Expected a second Control+R to find list orders, got", line)
+	}
+	if line := read(search, Key('b', 0), Key('z', 0), Key(KeyBackspace, 0), enter, enter); line != "post batch" {
+		t.Error("Expected Backspace to search for b again, got", line)
+	}
+	// Esc ends the search with the line as it was; other keys end it and act on the match
+	if line := read(Key('q', 0), search, Key('p', 0), Key(KeyEscape, 0), Key('!', 0), enter); line != "q!" {
+		t.Error("Expected Esc to restore the typed line, got", line)
+	}
+	if line := read(search, Key('o', 0), Key(KeyHome, 0), Key('#', 0), enter); line != "#list customers" {
+		t.Error("Expected Home to end the search and move to the start, got", line)
+	}
+}
+
+func TestUnitSecretInput(t *testing.T) {
+	SetScreen(NewVirtualScreen(3, 40))
+	defer SetScreen(nil)
+	defer SetKeySource(nil)
+	defer SetClipboard("")
+	typed := func(text string) []KeyEvent {
+		var keys []KeyEvent
+		for _, c := range []byte(text) {
+			keys = append(keys, Key(c, 0))
+		}
+		return append(keys, Key(KeyEnter, 0))
+	}
+	// The mask is echoed for each character and the line comes back as bytes
+	Locate(1, 5)
+	SetKeySource(&slowKeys{keys: typed("pin1")})
+	if line := SecretInput('*', 10); string(line) != "pin1" {
+		t.Errorf("Expected pin1, got %q", line)
+	}
+	if snap, _ := CaptureScreen(); snap.Line(1) != "     ****" {
+		t.Errorf("Expected the mask echoed, got %q", snap.Line(1))
+	}
+
+	// Without a mask nothing is echoed and the cursor stays at the start.  A kill does not reach the clipboard
+	// and the history is left alone.
+	Cls()
+	Locate(2, 3)
+	SetClipboard("keep")
+	var cursors []int
+	keys := &slowKeys{keys: append([]KeyEvent{Key('a', 0), Key('b', 0), Ctrl('U')}, typed("xyz")...)}
+	keys.before = func() {
+		snap, _ := CaptureScreen()
+		cursors = append(cursors, snap.CursorRow*100+snap.CursorCol)
+	}
+	SetKeySource(keys)
+	history := NewHistory(5)
+	editor := LineEditor{Secret: true, History: history}
+	line, err := editor.ReadBytes()
+	if string(line) != "xyz" || err != nil {
+		t.Errorf("Expected xyz, got %q %v", line, err)
+	}
+	if snap, _ := CaptureScreen(); strings.TrimSpace(snap.Text()) != "" {
+		t.Errorf("Expected nothing echoed, got %q", snap.Text())
+	}
+	for _, cursor := range cursors[1:] {
+		if cursor != 203 {
+			t.Errorf("Expected the cursor kept at 2,3, got %v", cursors)
+			break
+		}
+	}
+	if GetClipboard() != "keep" || len(history.Entries()) != 0 {
+		t.Errorf("Expected the secret kept from the clipboard and history, got %q %v", GetClipboard(), history.Entries())
+	}
+	// The edit buffer the bytes were built from is zeroed
+	for _, r := range editor.buf[:cap(editor.buf)] {
+		if r != 0 {
+			t.Errorf("Expected the edit buffer wiped, got %q", string(editor.buf[:cap(editor.buf)]))
+			break
+		}
+	}
+
+	// Secret fields refuse copy and cut and keep no undo
+	field := NewInputField("PIN", "", 8)
+	SecretInputField(&field, 0)
+	e := fieldEditor{field: &field}
+	e.key(ActionNone, Key('4', 0))
+	e.key(ActionNone, Key('2', 0))
+	e.key(ActionCopy, KeyEvent{})
+	e.key(ActionCut, KeyEvent{})
+	if GetClipboard() != "keep" || field.value != "42" || len(field.undo) != 0 {
+		t.Errorf("Expected copy and cut refused, got clipboard %q value %q", GetClipboard(), field.value)
+	}
+	drawField(&field, 0)
+	if snap, _ := CaptureScreen(); strings.Contains(snap.Line(0), "42") {
+		t.Errorf("Expected the secret field drawn blank, got %q", snap.Line(0))
+	}
+}
+
+func TestUnitHandleSignals(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGINT cannot be sent to the process on Windows")
+	}
+	stop := HandleSignals()
+	defer stop()
+	asked := make(chan struct{}, 1)
+	SetInterruptHandler(func() bool {
+		asked <- struct{}{}
+		return true
+	})
+	defer SetInterruptHandler(nil)
+	process, _ := os.FindProcess(os.Getpid())
+	if err := process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-asked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected SIGINT to ask the interrupt handler")
+	}
+}
+
+func TestUnitFieldNarrow(t *testing.T) {
+	SetScreen(NewVirtualScreen(3, 60))
+	defer SetScreen(nil)
+	var shown []string
+	keys := &slowKeys{keys: []KeyEvent{Key(KeyTab, 0), Key(KeyHome, 0), Key(KeyEscape, 0)}}
+	keys.before = func() {
+		snap, _ := CaptureScreen()
+		shown = append(shown, fmt.Sprintf("%s|%d", snap.Line(0), snap.CursorCol))
+	}
+	SetKeySource(keys)
+	defer SetKeySource(nil)
+	name, city := NewField("Name", "Acme Widgets International", 30), NewField("City", "Springfield Heights Township", 30)
+	if err := NewView(NewHBox(name, city)).Run(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Name: Acme Widgets International    City: Springfield Height|32",
+		"Name: Acme Widgets International    City:  Heights Township|59",
+		"Name: Acme Widgets International    City: Springfield Height|42",
+	}
+	if fmt.Sprintf("%q", shown) != fmt.Sprintf("%q", want) {
+		t.Errorf("Expected the narrow field to scroll to the cursor\n%q\ngot\n%q", want, shown)
+	}
+}
+
+func TestUnitViewError(t *testing.T) {
+	SetScreen(NewVirtualScreen(3, 40))
+	defer SetScreen(nil)
+	SetKeySource(&slowKeys{keys: []KeyEvent{Key(KeyF4, 0), Key(KeyEscape, 0)}})
+	defer SetKeySource(nil)
+	field := NewField("Due", "", 10)
+	field.Input.popup = func(*InputField) (string, bool, error) {
+		return "", false, ErrIdleTimeout
+	}
+	if err := NewView(field).Run(); err != ErrIdleTimeout {
+		t.Error("Expected the popup error to end the view, got", err)
+	}
+}
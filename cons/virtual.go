@@ -0,0 +1,160 @@
+package cons
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// VirtualScreen is a Screen kept in memory, i.e. for tests and capturing output without a console.
+// Output wraps at the right edge and scrolls at the bottom like the console.
+type VirtualScreen struct {
+	rows    int
+	cols    int
+	cells   [][]Cell
+	row     int
+	col     int
+	fg      int8
+	bg      int8
+	partial []byte
+}
+
+// NewVirtualScreen creates a blank virtual screen with white on black output
+func NewVirtualScreen(rows int, cols int) *VirtualScreen {
+	s := &VirtualScreen{fg: ColorWhite, bg: ColorBlack}
+	s.Resize(rows, cols)
+	return s
+}
+
+// blankRow returns a row of blanks in the current colors
+func (s *VirtualScreen) blankRow() []Cell {
+	row := make([]Cell, s.cols)
+	for i := range row {
+		row[i] = Cell{Char: ' ', Foreground: s.fg, Background: s.bg}
+	}
+	return row
+}
+
+// Write writes text at the cursor.  \n moves to the start of the next line, \r to the start of the line,
+// \b back a column and \t to the next multiple of 8 columns.
+func (s *VirtualScreen) Write(p []byte) (int, error) {
+	data := append(s.partial, p...)
+	s.partial = nil
+	for len(data) > 0 {
+		if !utf8.FullRune(data) {
+			s.partial = append([]byte(nil), data...)
+			break
+		}
+		r, size := utf8.DecodeRune(data)
+		data = data[size:]
+		switch r {
+		case '\n':
+			s.col = 0
+			s.newLine()
+		case '\r':
+			s.col = 0
+		case '\b':
+			if s.col > 0 {
+				s.col--
+			}
+		case '\a':
+		case '\t':
+			for {
+				s.put(' ')
+				if s.col%8 == 0 {
+					break
+				}
+			}
+		default:
+			s.put(r)
+		}
+	}
+	return len(p), nil
+}
+
+// put writes a character at the cursor and advances, wrapping at the right edge
+func (s *VirtualScreen) put(r rune) {
+	if s.col >= s.cols {
+		s.col = 0
+		s.newLine()
+	}
+	s.cells[s.row][s.col] = Cell{Char: r, Foreground: s.fg, Background: s.bg}
+	s.col++
+}
+
+// newLine moves the cursor down a row, scrolling the screen up at the bottom
+func (s *VirtualScreen) newLine() {
+	if s.row < s.rows-1 {
+		s.row++
+		return
+	}
+	copy(s.cells, s.cells[1:])
+	s.cells[s.rows-1] = s.blankRow()
+}
+
+// Size returns the number of rows and columns
+func (s *VirtualScreen) Size() (int, int, error) {
+	return s.rows, s.cols, nil
+}
+
+// Resize changes the number of rows and columns, keeping the text that still fits
+func (s *VirtualScreen) Resize(rows int, cols int) error {
+	if rows < 1 || cols < 1 {
+		return errors.New("screen size must be at least 1x1")
+	}
+	cells := make([][]Cell, rows)
+	s.rows, s.cols = rows, cols
+	for row := range cells {
+		cells[row] = s.blankRow()
+		if row < len(s.cells) {
+			copy(cells[row], s.cells[row])
+		}
+	}
+	s.cells = cells
+	s.row = min(s.row, rows-1)
+	s.col = min(s.col, cols-1)
+	return nil
+}
+
+// Cursor returns the cursor row and column
+func (s *VirtualScreen) Cursor() (int, int, error) {
+	return s.row, min(s.col, s.cols-1), nil
+}
+
+// Locate moves the cursor
+func (s *VirtualScreen) Locate(row int, col int) error {
+	if row < 0 || row >= s.rows || col < 0 || col >= s.cols {
+		return errors.New("position outside the screen")
+	}
+	s.row, s.col = row, col
+	return nil
+}
+
+// Color returns the colors used for output
+func (s *VirtualScreen) Color() (int8, int8, error) {
+	return s.fg, s.bg, nil
+}
+
+// SetColor changes the colors used for output
+func (s *VirtualScreen) SetColor(foreground int8, background int8) error {
+	s.fg, s.bg = foreground&15, background&15
+	return nil
+}
+
+// Clear fills the screen with blanks in the current colors and moves the cursor home
+func (s *VirtualScreen) Clear() error {
+	for row := range s.cells {
+		s.cells[row] = s.blankRow()
+	}
+	s.row, s.col = 0, 0
+	return nil
+}
+
+// Capture returns a copy of the screen
+func (s *VirtualScreen) Capture() (*Snapshot, error) {
+	snap := &Snapshot{Rows: s.rows, Cols: s.cols, Cells: make([][]Cell, s.rows)}
+	for row := range s.cells {
+		snap.Cells[row] = append([]Cell(nil), s.cells[row]...)
+	}
+	snap.CursorRow, snap.CursorCol, _ = s.Cursor()
+	return snap, nil
+}
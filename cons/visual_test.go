@@ -6,6 +6,7 @@ import (
 )
 
 func TestVisualForegroundBackground(t *testing.T) {
+	requireConsole(t)
 	var f, b int8
 	for b = 0; b < 8; b++ {
 		for f = 0; f < 16; f++ {
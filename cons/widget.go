@@ -0,0 +1,223 @@
+package cons
+
+// Widget is a component drawn in an area of the screen.  Containers place widgets with a layout and a View sends
+// keys to the widget that has the focus.
+type Widget interface {
+	// Place sets the area the widget is drawn in
+	Place(row int, col int, rows int, cols int)
+	// Size returns the rows and columns the widget wants.  Zero takes a share of what the layout has left.
+	Size() (rows int, cols int)
+	// Draw draws the widget in its area
+	Draw()
+	// HandleEvent handles a key while the widget has the focus.  It returns false if the key was not used
+	// so the view can handle it, i.e. Tab moving to the next widget.  An error ends the view, i.e.
+	// ErrInterrupted from a popup the key opened.
+	HandleEvent(key KeyEvent) (bool, error)
+	// Focus gives the widget the focus or takes it away.  It returns false if the widget does not take the
+	// focus, or cannot give it up because its value is not valid.
+	Focus(focused bool) bool
+}
+
+// Parent is a widget that holds other widgets, such as a Container
+type Parent interface {
+	Widget
+	// Children returns the widgets held, in focus order
+	Children() []Widget
+}
+
+// Base holds the area and focus of a widget.  Embed it in a widget to get Place, Size and Focus.
+type Base struct {
+	Row     int
+	Col     int
+	Rows    int
+	Cols    int
+	focused bool
+}
+
+// Place sets the area the widget is drawn in
+func (b *Base) Place(row int, col int, rows int, cols int) {
+	b.Row, b.Col, b.Rows, b.Cols = row, col, max(0, rows), max(0, cols)
+}
+
+// Size returns zero so the widget takes a share of the layout
+func (b *Base) Size() (int, int) {
+	return 0, 0
+}
+
+// Focus refuses the focus.  Widgets that take it override Focus and call SetFocused.
+func (b *Base) Focus(focused bool) bool {
+	return !focused
+}
+
+// Focused returns true if the widget has the focus
+func (b *Base) Focused() bool {
+	return b.focused
+}
+
+// SetFocused records whether the widget has the focus
+func (b *Base) SetFocused(focused bool) {
+	b.focused = focused
+}
+
+// View runs a tree of widgets filling the screen.  Keys go to the focused widget first.  Keys it does not use
+// that are bound to ActionNextField or ActionAccept move the focus to the next widget, ActionPrevField moves it
+// back and ActionCancel closes the view.  The widgets are placed again when the screen size changes.
+type View struct {
+	Root Widget
+	// Foreground and Background are the colors the screen is cleared to
+	Foreground int8
+	Background int8
+	focus      Widget
+	rows       int
+	cols       int
+	closed     bool
+}
+
+// NewView creates a view of root
+func NewView(root Widget) *View {
+	return &View{Root: root, Foreground: ColorWhite, Background: ColorBlue}
+}
+
+// Run shows the view until Close is called or it is canceled.  It returns ErrInterrupted for Control+C, and
+// the error of a widget handling a key.
+func (v *View) Run() error {
+	defer HoldScreen()()
+	v.closed = false
+	v.rows, v.cols = 0, 0
+	for !v.closed {
+		rows, cols, err := WindowSizeErr()
+		if err != nil {
+			return err
+		}
+		if rows != v.rows || cols != v.cols {
+			v.rows, v.cols = rows, cols
+			v.Root.Place(0, 0, rows, cols)
+			if v.focus == nil {
+				v.FocusNext()
+			}
+			v.Draw()
+		}
+		key, err := GetKeyErr()
+		if err != nil {
+			return err
+		}
+		if v.focus != nil {
+			used, err := v.focus.HandleEvent(key)
+			if err != nil {
+				return err
+			}
+			if used {
+				v.focus.Draw()
+				continue
+			}
+		}
+		switch GetKeyMap().Action(key) {
+		case ActionNextField, ActionAccept:
+			v.FocusNext()
+		case ActionPrevField:
+			v.FocusPrev()
+		case ActionCancel:
+			v.Close()
+		case ActionInterrupt:
+			if interrupted() {
+				return ErrInterrupted
+			}
+		default:
+			Beep()
+		}
+	}
+	return nil
+}
+
+// Draw clears the screen and draws all the widgets, the focused one last so it places the cursor
+func (v *View) Draw() {
+	SetColor(v.Foreground, v.Background)
+	Cls()
+	v.Root.Draw()
+	if v.focus != nil {
+		v.focus.Draw()
+	}
+}
+
+// Close ends Run once the current key is handled
+func (v *View) Close() {
+	v.closed = true
+}
+
+// Focused returns the widget with the focus, or nil if no widget takes it
+func (v *View) Focused() Widget {
+	return v.focus
+}
+
+// SetFocus moves the focus to w.  It returns false if w does not take the focus or the focused widget will
+// not give it up.
+func (v *View) SetFocus(w Widget) bool {
+	if w == v.focus {
+		return true
+	}
+	if !w.Focus(true) {
+		return false
+	}
+	return v.release(w)
+}
+
+// FocusNext moves the focus to the next widget that takes it, wrapping around to the first
+func (v *View) FocusNext() {
+	v.moveFocus(1)
+}
+
+// FocusPrev moves the focus to the prior widget that takes it, wrapping around to the last
+func (v *View) FocusPrev() {
+	v.moveFocus(-1)
+}
+
+// moveFocus moves the focus step widgets along the focus chain, skipping widgets that do not take it
+func (v *View) moveFocus(step int) {
+	chain := focusChain(v.Root, nil)
+	current := -1
+	for i, w := range chain {
+		if w == v.focus {
+			current = i
+		}
+	}
+	if current < 0 && step < 0 {
+		current = 0
+	}
+	for n := 1; n <= len(chain); n++ {
+		w := chain[((current+n*step)%len(chain)+len(chain))%len(chain)]
+		if w == v.focus {
+			return
+		}
+		if w.Focus(true) {
+			v.release(w)
+			return
+		}
+	}
+}
+
+// release takes the focus from the focused widget and gives it to w, which has already taken it.  If the
+// focused widget will not give it up w loses the focus again.
+func (v *View) release(w Widget) bool {
+	if v.focus != nil {
+		if !v.focus.Focus(false) {
+			w.Focus(false)
+			Beep()
+			return false
+		}
+		v.focus.Draw()
+	}
+	v.focus = w
+	w.Draw()
+	return true
+}
+
+// focusChain lists the widgets under w that are not parents, in focus order
+func focusChain(w Widget, chain []Widget) []Widget {
+	if p, ok := w.(Parent); ok {
+		for _, child := range p.Children() {
+			chain = focusChain(child, chain)
+		}
+		return chain
+	}
+	return append(chain, w)
+}
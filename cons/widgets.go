@@ -0,0 +1,288 @@
+package cons
+
+import (
+	"lib/str"
+	"strings"
+)
+
+// Label is a widget showing lines of text
+type Label struct {
+	Base
+	Text string
+	// Foreground and Background are the text colors
+	Foreground int8
+	Background int8
+}
+
+// NewLabel creates a label showing text
+func NewLabel(text string) *Label {
+	return &Label{Text: text, Foreground: ColorWhite, Background: ColorBlue}
+}
+
+// Size returns the number of lines and the longest line
+func (l *Label) Size() (int, int) {
+	lines := strings.Split(l.Text, "\n")
+	cols := 0
+	for _, line := range lines {
+		cols = max(cols, len([]rune(line)))
+	}
+	return len(lines), cols
+}
+
+// Draw draws the text, cut to the label's area
+func (l *Label) Draw() {
+	SetColor(l.Foreground, l.Background)
+	lines := strings.Split(l.Text, "\n")
+	for r := 0; r < l.Rows; r++ {
+		line := ""
+		if r < len(lines) {
+			line = lines[r]
+		}
+		Locate(l.Row+r, l.Col)
+		Print(fitText(line, l.Cols))
+	}
+}
+
+// HandleEvent uses no keys
+func (l *Label) HandleEvent(key KeyEvent) (bool, error) {
+	return false, nil
+}
+
+// Button is a widget that calls OnPress when Enter or Space is pressed while it has the focus
+type Button struct {
+	Base
+	Text    string
+	OnPress func()
+	// Foreground and Background are the button colors
+	Foreground int8
+	Background int8
+	// FocusForeground and FocusBackground are the colors while it has the focus
+	FocusForeground int8
+	FocusBackground int8
+}
+
+// NewButton creates a button labeled text
+func NewButton(text string, onPress func()) *Button {
+	return &Button{Text: text, OnPress: onPress, Foreground: ColorBlack, Background: ColorWhite,
+		FocusForeground: ColorBlack, FocusBackground: ColorCyan}
+}
+
+// Size returns one row wide enough for the text in brackets
+func (b *Button) Size() (int, int) {
+	return 1, len([]rune(b.Text)) + 4
+}
+
+// Draw draws the text in brackets
+func (b *Button) Draw() {
+	if b.Rows < 1 {
+		return
+	}
+	if b.Focused() {
+		SetColor(b.FocusForeground, b.FocusBackground)
+	} else {
+		SetColor(b.Foreground, b.Background)
+	}
+	Locate(b.Row, b.Col)
+	Print(fitText("[ "+b.Text+" ]", b.Cols))
+	Locate(b.Row, b.Col+min(2, b.Cols))
+}
+
+// HandleEvent presses the button for Enter or Space
+func (b *Button) HandleEvent(key KeyEvent) (bool, error) {
+	if key.Key != KeyEnter && key.Key != ' ' || key.Modifier&(KeyControl|KeyAlt) != 0 {
+		return false, nil
+	}
+	if b.OnPress != nil {
+		b.OnPress()
+	}
+	return true, nil
+}
+
+// Focus takes the focus
+func (b *Button) Focus(focused bool) bool {
+	b.SetFocused(focused)
+	return true
+}
+
+// Field is a widget for entering a value with a prompt.  Input can be set up like any other field, i.e. with
+// ValidatedInputField or DateInputField, and keys are those of StartEntry.
+type Field struct {
+	Base
+	Input InputField
+	// PromptWidth is the width of the prompt column so the values of stacked fields line up.  Zero fits the prompt.
+	PromptWidth int
+	// Foreground and Background are the prompt colors
+	Foreground int8
+	Background int8
+	// FieldForeground and FieldBackground are the value colors
+	FieldForeground int8
+	FieldBackground int8
+	editor          fieldEditor
+}
+
+// NewField creates a field entering value, at most size characters, after prompt
+func NewField(prompt string, value string, size int) *Field {
+	return &Field{Input: NewInputField(prompt, value, size), Foreground: ColorWhite, Background: ColorBlue,
+		FieldForeground: ColorBlack, FieldBackground: ColorWhite}
+}
+
+// Value returns the entered value
+func (f *Field) Value() string {
+	return FieldValue(&f.Input)
+}
+
+// promptWidth returns the width of the prompt column
+func (f *Field) promptWidth() int {
+	return max(f.PromptWidth, len([]rune(f.Input.prompt)))
+}
+
+// Size returns one row wide enough for the prompt and value
+func (f *Field) Size() (int, int) {
+	return 1, f.promptWidth() + 2 + f.Input.size
+}
+
+// Place sets the area and puts the value after the prompt
+func (f *Field) Place(row int, col int, rows int, cols int) {
+	f.Base.Place(row, col, rows, cols)
+	f.Input.row = row
+	f.Input.col = col + f.promptWidth() + 2
+	f.Input.hidden = max(0, f.Input.size-(col+cols-f.Input.col))
+}
+
+// Draw draws the prompt and the value, leaving the cursor in the value while it has the focus
+func (f *Field) Draw() {
+	if f.Rows < 1 {
+		return
+	}
+	SetColor(f.Foreground, f.Background)
+	Locate(f.Row, f.Col)
+	Print(fitText(str.LeftPad(f.Input.prompt+":", f.promptWidth()+1, ".")+" ", f.Cols))
+	// a value wider than the area scrolls to keep the cursor in view, and shows its start without the focus
+	width := f.Input.size - f.Input.hidden
+	if f.Input.hidden == 0 || !f.Focused() {
+		f.Input.scroll = 0
+	} else {
+		f.Input.scroll = max(0, max(min(f.Input.scroll, f.editor.offset), f.editor.offset-width+1))
+	}
+	SetColor(f.FieldForeground, f.FieldBackground)
+	drawField(&f.Input, f.editor.offset)
+	if f.Focused() {
+		f.editor.locate()
+	}
+}
+
+// HandleEvent applies keys that edit the value.  It returns the error of a popup, i.e. ErrInterrupted.
+func (f *Field) HandleEvent(key KeyEvent) (bool, error) {
+	SetColor(f.FieldForeground, f.FieldBackground)
+	return f.editor.key(GetKeyMap().Action(key), key)
+}
+
+// Focus takes the focus, and gives it up only if the value is valid
+func (f *Field) Focus(focused bool) bool {
+	if !focused {
+		if !isFieldValid(&f.Input) {
+			return false
+		}
+		f.Input.selecting = false
+		f.Input.value = strings.TrimRight(f.Input.value, " \t\r\n")
+	} else if !f.Focused() {
+		f.Input.original = f.Input.value
+		f.Input.undo, f.Input.redo = nil, nil
+		f.editor = fieldEditor{field: &f.Input, offset: len([]rune(f.Input.value))}
+	}
+	f.SetFocused(focused)
+	return true
+}
+
+// Menu is a widget listing choices.  Up and Down move, typing a letter selects the next choice starting with
+// it and Enter calls OnChoose.  Moving past the first or last choice moves the focus.
+type Menu struct {
+	Base
+	Items    []string
+	Selected int
+	OnChoose func(index int)
+	// Foreground and Background are the item colors
+	Foreground int8
+	Background int8
+	// SelectForeground and SelectBackground are the colors of the selected item while the menu has the focus
+	SelectForeground int8
+	SelectBackground int8
+	top              int
+}
+
+// NewMenu creates a menu of items
+func NewMenu(onChoose func(index int), items ...string) *Menu {
+	return &Menu{Items: items, OnChoose: onChoose, Foreground: ColorWhite, Background: ColorBlue,
+		SelectForeground: ColorBlack, SelectBackground: ColorCyan}
+}
+
+// Size returns a row per item wide enough for the longest
+func (m *Menu) Size() (int, int) {
+	cols := 0
+	for _, item := range m.Items {
+		cols = max(cols, len([]rune(item)))
+	}
+	return len(m.Items), cols + 2
+}
+
+// Draw draws the visible items, highlighting the selected one
+func (m *Menu) Draw() {
+	m.top = max(min(m.top, m.Selected), m.Selected-m.Rows+1)
+	for r := 0; r < m.Rows; r++ {
+		index := m.top + r
+		line := ""
+		if index < len(m.Items) {
+			line = " " + m.Items[index]
+		}
+		SetColor(m.Foreground, m.Background)
+		if index == m.Selected {
+			if m.Focused() {
+				SetColor(m.SelectForeground, m.SelectBackground)
+			} else {
+				SetColor(m.Background, m.Foreground)
+			}
+		}
+		Locate(m.Row+r, m.Col)
+		Print(fitText(line, m.Cols))
+	}
+	Locate(m.Row+m.Selected-m.top, m.Col)
+}
+
+// HandleEvent moves through and chooses items
+func (m *Menu) HandleEvent(key KeyEvent) (bool, error) {
+	if key.Modifier&(KeyControl|KeyAlt) != 0 || len(m.Items) == 0 {
+		return false, nil
+	}
+	switch {
+	case key.Key == KeyUp && m.Selected > 0:
+		m.Selected--
+	case key.Key == KeyDown && m.Selected < len(m.Items)-1:
+		m.Selected++
+	case key.Key == KeyHome:
+		m.Selected = 0
+	case key.Key == KeyEnd:
+		m.Selected = len(m.Items) - 1
+	case key.Key == KeyEnter:
+		if m.OnChoose != nil {
+			m.OnChoose(m.Selected)
+		}
+	case isPrintable(key) && key.Key != ' ':
+		for n := 1; n <= len(m.Items); n++ {
+			index := (m.Selected + n) % len(m.Items)
+			if strings.HasPrefix(strings.ToLower(m.Items[index]), strings.ToLower(string(keyRune(key)))) {
+				m.Selected = index
+				return true, nil
+			}
+		}
+		Beep()
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// Focus takes the focus
+func (m *Menu) Focus(focused bool) bool {
+	m.SetFocused(focused)
+	return true
+}
@@ -0,0 +1,133 @@
+package cons
+
+import (
+	"strings"
+)
+
+// Window is a rectangular area of the screen with an optional border and title.  Widgets such as
+// Progress are placed inside it.  Opening a window saves the screen under it and closing restores it.
+type Window struct {
+	// Row and Col are the top left corner, including the border
+	Row int
+	Col int
+	// Rows and Cols are the size, including the border
+	Rows int
+	Cols int
+	// Border is one of the LineStyle constants
+	Border int
+	// Title is drawn centered in the top border
+	Title string
+	// Foreground and Background are the window colors
+	Foreground int8
+	Background int8
+	// Shadow draws a drop shadow below and right of the window when it is opened
+	Shadow bool
+	saved  *Snapshot
+}
+
+// NewWindow creates a window at row, col that is rows by cols including the border
+func NewWindow(row int, col int, rows int, cols int, border int, foreground int8, background int8) *Window {
+	return &Window{Row: row, Col: col, Rows: rows, Cols: cols, Border: border, Foreground: foreground, Background: background}
+}
+
+// CenteredWindow creates a window that is rows by cols including the border, centered on the screen
+func CenteredWindow(rows int, cols int, border int, foreground int8, background int8) *Window {
+	screenRows, screenCols, _ := WindowSizeErr()
+	rows = min(rows, screenRows)
+	cols = min(cols, screenCols)
+	return NewWindow((screenRows-rows)/2, (screenCols-cols)/2, rows, cols, border, foreground, background)
+}
+
+// Inner returns the area inside the border
+func (w *Window) Inner() (row int, col int, rows int, cols int) {
+	if w.Border == LineStyleNone {
+		return w.Row, w.Col, w.Rows, w.Cols
+	}
+	return w.Row + 1, w.Col + 1, w.Rows - 2, w.Cols - 2
+}
+
+// Open saves the screen under the window, then draws the border and clears the inside
+func (w *Window) Open() {
+	w.saved, _ = screen.Capture()
+	w.Draw()
+	if w.Shadow {
+		DrawShadow(w.Row, w.Col, w.Rows, w.Cols)
+		w.Locate(0, 0)
+	}
+}
+
+// Draw draws the border and title and clears the inside
+func (w *Window) Draw() {
+	SetColor(w.Foreground, w.Background)
+	box := GetBoxChars(w.Border)
+	for r := 0; r < w.Rows; r++ {
+		Locate(w.Row+r, w.Col)
+		switch {
+		case w.Border == LineStyleNone:
+			Print(strings.Repeat(" ", w.Cols))
+		case r == 0:
+			Print(box.TopLeft, w.topLine(box.Horizontal), box.TopRight)
+		case r == w.Rows-1:
+			Print(box.BottomLeft, strings.Repeat(box.Horizontal, w.Cols-2), box.BottomRight)
+		default:
+			Print(box.Vertical, strings.Repeat(" ", w.Cols-2), box.Vertical)
+		}
+	}
+	row, col, _, _ := w.Inner()
+	Locate(row, col)
+}
+
+// topLine returns the top border between the corners with the title centered in it
+func (w *Window) topLine(horizontal string) string {
+	width := w.Cols - 2
+	title := []rune(w.Title)
+	if len(title) == 0 || width < 3 {
+		return strings.Repeat(horizontal, width)
+	}
+	if len(title) > width-2 {
+		title = title[:width-2]
+	}
+	left := (width - len(title) - 2) / 2
+	return strings.Repeat(horizontal, left) + " " + string(title) + " " + strings.Repeat(horizontal, width-left-len(title)-2)
+}
+
+// Close restores the screen that was under the window when it was opened
+func (w *Window) Close() {
+	if w.saved == nil {
+		return
+	}
+	fg, bg, _ := GetColorErr()
+	rows, cols := w.Rows, w.Cols
+	if w.Shadow {
+		rows, cols = rows+1, cols+2
+	}
+	restoreArea(w.saved, w.Row, w.Col, rows, cols)
+	SetColor(fg, bg)
+	Locate(w.saved.CursorRow, w.saved.CursorCol)
+	w.saved = nil
+}
+
+// Locate moves the cursor to row, col inside the window
+func (w *Window) Locate(row int, col int) {
+	top, left, _, _ := w.Inner()
+	Locate(top+row, left+col)
+}
+
+// restoreArea redraws an area of the screen from a snapshot
+func restoreArea(snap *Snapshot, row int, col int, rows int, cols int) {
+	for r := max(row, 0); r < min(row+rows, snap.Rows); r++ {
+		cells := snap.Cells[r]
+		end := min(col+cols, snap.Cols)
+		for c := max(col, 0); c < end; {
+			start := c
+			var sb strings.Builder
+			for c < end && cells[c].Foreground == cells[start].Foreground && cells[c].Background == cells[start].Background {
+				sb.WriteRune(cells[c].Char)
+				c++
+			}
+			SetColor(cells[start].Foreground, cells[start].Background)
+			Locate(r, start)
+			Print(sb.String())
+		}
+	}
+}
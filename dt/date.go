@@ -51,5 +51,5 @@ func Stod(value string) (time.Time, error) {
 
 // Dtos returns a short date formatted as yyyy-MM-dd
 func Dtos(dt time.Time) string {
-	return fmt.Sprintf("%4d-%2d-%2d", dt.Year(), dt.Month(), dt.Day())
+	return fmt.Sprintf("%04d-%02d-%02d", dt.Year(), dt.Month(), dt.Day())
 }
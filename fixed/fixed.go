@@ -3,6 +3,7 @@ package fixed
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -27,24 +28,41 @@ func FromFloat64(v float64) Fixed {
 	return Fixed{value: int64(v * 10000.0)}
 }
 
-// FromString creates a Fixed from a string
+// FromString creates a Fixed from a string such as "-12.5".  Decimals past the fourth are dropped.  Numbers
+// whose magnitude does not fit, beyond 922337203685477.5807, are an error.
 func FromString(value string) (Fixed, error) {
-	pieces := strings.Split(value, ".")
+	negative := strings.HasPrefix(value, "-")
+	magnitude := value
+	if negative || strings.HasPrefix(value, "+") {
+		magnitude = magnitude[1:]
+	}
+	pieces := strings.Split(magnitude, ".")
 	if len(pieces) == 1 {
 		pieces = append(pieces, "0000")
 	}
-	if len([]rune(pieces[1])) < 4 {
-		pieces[1] = string([]rune(pieces[1] + "0000")[0:4])
+	if magnitude == "" || len(pieces) != 2 || pieces[0] == "" && pieces[1] == "" || strings.HasPrefix(pieces[1], "+") || strings.HasPrefix(pieces[1], "-") {
+		return Fixed{}, fmt.Errorf("invalid number %q", value)
+	}
+	if pieces[0] == "" {
+		pieces[0] = "0"
 	}
-	whole, err := strconv.ParseInt(pieces[0], 10, 64)
+	pieces[1] = string([]rune(pieces[1] + "0000")[0:4])
+	whole, err := strconv.ParseUint(pieces[0], 10, 63)
 	if err != nil {
 		return Fixed{}, err
 	}
-	frac, err := strconv.ParseInt(pieces[1], 10, 64)
+	frac, err := strconv.ParseUint(pieces[1], 10, 63)
 	if err != nil {
 		return Fixed{}, err
 	}
-	return Fixed{value: whole*10000 + frac}, nil
+	if whole > (math.MaxInt64-frac)/10000 {
+		return Fixed{}, fmt.Errorf("number %q is out of range", value)
+	}
+	f := Fixed{value: int64(whole)*10000 + int64(frac)}
+	if negative {
+		f.value = -f.value
+	}
+	return f, nil
 }
 
 // FromFixed clones a Fixed
@@ -71,10 +89,17 @@ func toThousands(n int64, sep byte) string {
 	}
 }
 
-// ToString outputs fixed as string with decimals, optional separator left padded to width
+// ToString outputs fixed as string with decimals, optional separator left padded to width.
+// Extra decimals are truncated.
 func (f *Fixed) ToString(decimals int, sep byte, width int) string {
-	frac := f.value % 10000
-	whole := f.value / 10000
+	sign := ""
+	v := f.value
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	frac := v % 10000
+	whole := v / 10000
 	if decimals > 4 {
 		decimals = 4
 	}
@@ -87,8 +112,7 @@ func (f *Fixed) ToString(decimals int, sep byte, width int) string {
 	}
 	fr := ""
 	if decimals > 0 {
-		format := fmt.Sprintf("%s%dd", "%", decimals)
-		fr = fmt.Sprintf(format, frac)
+		fr = fmt.Sprintf(".%0*d", decimals, frac)
 	}
 	wh := ""
 	if sep != 0 {
@@ -96,14 +120,12 @@ func (f *Fixed) ToString(decimals int, sep byte, width int) string {
 	} else {
 		wh = strconv.FormatInt(whole, 10)
 	}
-	out := wh + fr
+	out := sign + wh + fr
 
-	if width > 0 {
-		out = strings.Repeat(" ", width) + out
-		r := []rune(out)
-		out = string(r[len(r)-width : len(r)])
+	if width > len(out) {
+		out = strings.Repeat(" ", width-len(out)) + out
 	}
-	return wh + fr
+	return out
 }
 
 // Compare compares two Fixed
@@ -151,6 +173,8 @@ func (f *Fixed) Round(decimals int) error {
 	}
 	if round >= 5 {
 		f.value++
+	} else if round <= -5 {
+		f.value--
 	}
 	for i := decimals; i < 4; i++ {
 		f.value *= 10
@@ -8,11 +8,11 @@ import (
 func LeftPad(value string, length int, pad string) string {
 	vl := len([]rune(value))
 	pl := len([]rune(pad))
-	result := value + strings.Repeat(pad, (length-vl)/pl)
+	result := value + strings.Repeat(pad, max(0, (length-vl)/pl))
 	if len([]rune(result)) < pl {
 		result += pad
 	}
-	return result[:length]
+	return string([]rune(result)[:length])
 }
 
 // Text combines multiple strings